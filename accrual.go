@@ -0,0 +1,269 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// accrual is what an address has earned of a single denom since the exporter
+// started, derived from the changes of its outstanding amount.
+type accrual struct {
+	Outstanding float64
+	Earned      float64
+}
+
+// accruals are keyed by the address and then by the denom.
+type accruals map[string]map[string]*accrual
+
+// AccrualTracker follows the outstanding commission of the validators and the
+// pending rewards of the wallets from the config between the refreshes. As
+// they drop to zero whenever withdrawn, a decrease is taken as a withdrawal
+// and only what's left is counted as earned since then, so the earned
+// amounts only ever grow.
+type AccrualTracker struct {
+	mutex sync.RWMutex
+
+	commission            accruals
+	rewards               accruals
+	commissionWithdrawals map[string]float64
+	rewardsWithdrawals    map[string]float64
+	height                int64
+}
+
+var accrualTracker = &AccrualTracker{
+	commission:            accruals{},
+	rewards:               accruals{},
+	commissionWithdrawals: map[string]float64{},
+	rewardsWithdrawals:    map[string]float64{},
+}
+
+func (t *AccrualTracker) Refresh(grpcConn NodeConn) error {
+	scrape := NewScrape(grpcConn)
+	ctx := scrape.Context()
+	distributionClient := distributiontypes.NewQueryClient(grpcConn)
+
+	for _, target := range ValidatorTargets {
+		resolvedAddress, err := resolveAddress(target.Address, scrape.Validators)
+		if err != nil {
+			log.Error().
+				Str("address", target.Address).
+				Err(err).
+				Msg("Could not parse validator address")
+			continue
+		}
+
+		address := resolvedAddress.Validator.String()
+
+		response, err := distributionClient.ValidatorCommission(
+			ctx,
+			&distributiontypes.QueryValidatorCommissionRequest{ValidatorAddress: address},
+		)
+		if err != nil {
+			log.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get validator commission")
+			continue
+		}
+
+		t.update(t.commission, t.commissionWithdrawals, address, response.Commission.Commission)
+	}
+
+	for _, target := range WalletTargets {
+		resolvedAddress, err := resolveAddress(target.Address, scrape.Validators)
+		if err != nil {
+			log.Error().
+				Str("address", target.Address).
+				Err(err).
+				Msg("Could not parse address")
+			continue
+		}
+
+		address := resolvedAddress.Account.String()
+
+		response, err := distributionClient.DelegationTotalRewards(
+			ctx,
+			&distributiontypes.QueryDelegationTotalRewardsRequest{DelegatorAddress: address},
+		)
+		if err != nil {
+			log.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get rewards")
+			continue
+		}
+
+		t.update(t.rewards, t.rewardsWithdrawals, address, response.Total)
+	}
+
+	t.mutex.Lock()
+	t.height = scrape.Height
+	t.mutex.Unlock()
+
+	return nil
+}
+
+func (t *AccrualTracker) update(
+	addressAccruals accruals,
+	withdrawals map[string]float64,
+	address string,
+	coins sdk.DecCoins,
+) {
+	outstanding := make(map[string]float64, len(coins))
+	for _, coin := range coins {
+		value, err := strconv.ParseFloat(coin.Amount.String(), 64)
+		if err != nil {
+			log.Error().
+				Str("address", address).
+				Str("denom", coin.Denom).
+				Err(err).
+				Msg("Could not parse outstanding amount")
+			return
+		}
+
+		outstanding[coin.Denom] = value / DenomCoefficient
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	previous, ok := addressAccruals[address]
+	if !ok {
+		// Nothing is known about what was earned before the first refresh.
+		previous = make(map[string]*accrual, len(outstanding))
+		for denom, value := range outstanding {
+			previous[denom] = &accrual{Outstanding: value}
+		}
+
+		addressAccruals[address] = previous
+		withdrawals[address] = 0
+		return
+	}
+
+	// A denom missing from the response was withdrawn entirely.
+	for denom := range previous {
+		if _, ok := outstanding[denom]; !ok {
+			outstanding[denom] = 0
+		}
+	}
+
+	var withdrawn bool
+	for denom, value := range outstanding {
+		current, ok := previous[denom]
+		if !ok {
+			current = &accrual{}
+			previous[denom] = current
+		}
+
+		if value >= current.Outstanding {
+			current.Earned += value - current.Outstanding
+		} else {
+			// Whatever was earned between the last refresh and the
+			// withdrawal is not known, only what has accrued since.
+			current.Earned += value
+			withdrawn = true
+		}
+
+		current.Outstanding = value
+	}
+
+	if withdrawn {
+		withdrawals[address]++
+	}
+}
+
+func AccrualHandler(w http.ResponseWriter, r *http.Request) {
+	requestStart := time.Now()
+
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	validatorCommissionEarnedCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "cosmos_validator_commission_earned_total",
+			Help:        "Commission earned by the Cosmos-based blockchain validator since the exporter started",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "denom"},
+	)
+
+	validatorCommissionWithdrawalsCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "cosmos_validator_commission_withdrawals_total",
+			Help:        "Commission withdrawals of the Cosmos-based blockchain validator detected since the exporter started",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address"},
+	)
+
+	walletRewardsEarnedCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "cosmos_wallet_rewards_earned_total",
+			Help:        "Rewards earned by the Cosmos-based blockchain wallet since the exporter started",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "denom"},
+	)
+
+	walletRewardsWithdrawalsCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "cosmos_wallet_rewards_withdrawals_total",
+			Help:        "Rewards withdrawals of the Cosmos-based blockchain wallet detected since the exporter started",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address"},
+	)
+
+	snapshotHeightGauge := newSnapshotHeightGauge()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(validatorCommissionEarnedCounter)
+	registry.MustRegister(validatorCommissionWithdrawalsCounter)
+	registry.MustRegister(walletRewardsEarnedCounter)
+	registry.MustRegister(walletRewardsWithdrawalsCounter)
+	registry.MustRegister(snapshotHeightGauge)
+
+	setAccruals := func(counter *prometheus.CounterVec, addressAccruals accruals) {
+		for address, denomAccruals := range addressAccruals {
+			for denom, accrual := range denomAccruals {
+				counter.With(prometheus.Labels{
+					"address": address,
+					"denom":   denom,
+				}).Add(accrual.Earned)
+			}
+		}
+	}
+
+	setWithdrawals := func(counter *prometheus.CounterVec, withdrawals map[string]float64) {
+		for address, count := range withdrawals {
+			counter.With(prometheus.Labels{
+				"address": address,
+			}).Add(count)
+		}
+	}
+
+	accrualTracker.mutex.RLock()
+	setAccruals(validatorCommissionEarnedCounter, accrualTracker.commission)
+	setAccruals(walletRewardsEarnedCounter, accrualTracker.rewards)
+	setWithdrawals(validatorCommissionWithdrawalsCounter, accrualTracker.commissionWithdrawals)
+	setWithdrawals(walletRewardsWithdrawalsCounter, accrualTracker.rewardsWithdrawals)
+	setSnapshotHeight(snapshotHeightGauge, "accrual", accrualTracker.height)
+	accrualTracker.mutex.RUnlock()
+
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+	sublogger.Info().
+		Str("method", "GET").
+		Str("endpoint", "/metrics/accrual").
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestAccrualTrackerUpdate(t *testing.T) {
+	denomCoefficient := DenomCoefficient
+	t.Cleanup(func() { DenomCoefficient = denomCoefficient })
+	DenomCoefficient = 1
+
+	coins := func(amounts map[string]int64) sdk.DecCoins {
+		var decCoins sdk.DecCoins
+		for denom, amount := range amounts {
+			decCoins = decCoins.Add(sdk.NewDecCoin(denom, sdkmath.NewInt(amount)))
+		}
+		return decCoins
+	}
+
+	tests := []struct {
+		name        string
+		refreshes   []map[string]int64
+		earned      map[string]float64
+		withdrawals float64
+	}{
+		{
+			name:      "first refresh earns nothing",
+			refreshes: []map[string]int64{{"uxprt": 100}},
+			earned:    map[string]float64{"uxprt": 0},
+		},
+		{
+			name:      "growing outstanding",
+			refreshes: []map[string]int64{{"uxprt": 100}, {"uxprt": 150}, {"uxprt": 160}},
+			earned:    map[string]float64{"uxprt": 60},
+		},
+		{
+			name:        "partial withdrawal",
+			refreshes:   []map[string]int64{{"uxprt": 100}, {"uxprt": 150}, {"uxprt": 20}},
+			earned:      map[string]float64{"uxprt": 70},
+			withdrawals: 1,
+		},
+		{
+			name:        "denom withdrawn entirely",
+			refreshes:   []map[string]int64{{"uxprt": 100, "uatom": 10}, {"uxprt": 110}},
+			earned:      map[string]float64{"uxprt": 10, "uatom": 0},
+			withdrawals: 1,
+		},
+		{
+			name:      "new denom",
+			refreshes: []map[string]int64{{"uxprt": 100}, {"uxprt": 100, "uatom": 5}},
+			earned:    map[string]float64{"uxprt": 0, "uatom": 5},
+		},
+		{
+			name:        "withdrawals counted once per refresh",
+			refreshes:   []map[string]int64{{"uxprt": 100, "uatom": 10}, {"uxprt": 1, "uatom": 1}, {"uxprt": 0}},
+			earned:      map[string]float64{"uxprt": 1, "uatom": 1},
+			withdrawals: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker := &AccrualTracker{
+				rewards:            accruals{},
+				rewardsWithdrawals: map[string]float64{},
+			}
+
+			for _, refresh := range test.refreshes {
+				tracker.update(tracker.rewards, tracker.rewardsWithdrawals, "wallet", coins(refresh))
+			}
+
+			for denom, expected := range test.earned {
+				if earned := tracker.rewards["wallet"][denom].Earned; earned != expected {
+					t.Errorf("earned %f of %s, expected %f", earned, denom, expected)
+				}
+			}
+
+			if withdrawals := tracker.rewardsWithdrawals["wallet"]; withdrawals != test.withdrawals {
+				t.Errorf("got %f withdrawals, expected %f", withdrawals, test.withdrawals)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/spf13/cobra"
+)
+
+// ResolvedAddress is the same entity in all the address forms known for it.
+// Consensus is only set if the validator was looked up in the validator set.
+type ResolvedAddress struct {
+	Account   sdk.AccAddress
+	Validator sdk.ValAddress
+	Consensus sdk.ConsAddress
+}
+
+// resolveAddress accepts an account, validator operator or consensus address
+// in bech32, or a consensus address in hex, and converts it to the other
+// forms. Consensus addresses are resolved through the consensus pubkeys of
+// the validator set, which is only fetched in that case.
+func resolveAddress(
+	address string,
+	getValidators func() ([]stakingtypes.Validator, error),
+) (*ResolvedAddress, error) {
+	hrp, addressBytes, err := bech32.DecodeAndConvert(address)
+	if err != nil {
+		consensusBytes, hexErr := hex.DecodeString(address)
+		if hexErr != nil || len(consensusBytes) != 20 {
+			return nil, fmt.Errorf("not a bech32 or a hex consensus address: %w", err)
+		}
+
+		return resolveConsensusAddress(sdk.ConsAddress(consensusBytes), getValidators)
+	}
+
+	switch hrp {
+	case AccountPrefix:
+		return &ResolvedAddress{
+			Account:   sdk.AccAddress(addressBytes),
+			Validator: sdk.ValAddress(addressBytes),
+		}, nil
+	case ValidatorPrefix:
+		return &ResolvedAddress{
+			Account:   sdk.AccAddress(addressBytes),
+			Validator: sdk.ValAddress(addressBytes),
+		}, nil
+	case ConsensusNodePrefix:
+		return resolveConsensusAddress(sdk.ConsAddress(addressBytes), getValidators)
+	default:
+		return nil, fmt.Errorf("unexpected bech32 prefix %q", hrp)
+	}
+}
+
+func resolveConsensusAddress(
+	consAddr sdk.ConsAddress,
+	getValidators func() ([]stakingtypes.Validator, error),
+) (*ResolvedAddress, error) {
+	validators, err := getValidators()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, validator := range validators {
+		if !bytes.Equal(getValidatorConsAddr(validator, log), consAddr) {
+			continue
+		}
+
+		valAddr, err := sdk.ValAddressFromBech32(validator.OperatorAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ResolvedAddress{
+			Account:   sdk.AccAddress(valAddr),
+			Validator: valAddr,
+			Consensus: consAddr,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no validator with consensus address %s", consAddr.String())
+}
+
+var addressCmd = &cobra.Command{
+	Use:   "address [address]",
+	Short: "Convert an address between the account, validator operator and consensus forms",
+	Long: "Convert an account, validator operator or consensus address, in bech32 or hex, " +
+		"to the other forms. Consensus addresses are resolved through the node's validator set.",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setLogger()
+		setBechConfig()
+
+		grpcConn := dialNode()
+		defer grpcConn.Close()
+
+		scrape := NewScrape(grpcConn)
+
+		resolved, err := resolveAddress(strings.TrimSpace(args[0]), scrape.Validators)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Could not resolve address")
+		}
+
+		if resolved.Consensus == nil {
+			if validators, err := scrape.Validators(); err != nil {
+				log.Warn().Err(err).Msg("Could not get validators, not resolving the consensus address")
+			} else {
+				for _, validator := range validators {
+					if validator.OperatorAddress == resolved.Validator.String() {
+						resolved.Consensus = getValidatorConsAddr(validator, log)
+						break
+					}
+				}
+			}
+		}
+
+		fmt.Printf("account:   %s\n", resolved.Account.String())
+		fmt.Printf("validator: %s\n", resolved.Validator.String())
+		if resolved.Consensus != nil {
+			fmt.Printf("consensus: %s\n", resolved.Consensus.String())
+			fmt.Printf("hex:       %s\n", strings.ToUpper(hex.EncodeToString(resolved.Consensus)))
+		}
+	},
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+func TestResolveAddress(t *testing.T) {
+	accountPrefix, validatorPrefix, consensusNodePrefix := AccountPrefix, ValidatorPrefix, ConsensusNodePrefix
+	t.Cleanup(func() {
+		AccountPrefix, ValidatorPrefix, ConsensusNodePrefix = accountPrefix, validatorPrefix, consensusNodePrefix
+	})
+
+	AccountPrefix = "persistence"
+	ValidatorPrefix = "persistencevaloper"
+	ConsensusNodePrefix = "persistencevalcons"
+
+	operatorBytes := bytes.Repeat([]byte{1}, 20)
+	pubkey := ed25519.GenPrivKeyFromSecret([]byte("validator")).PubKey()
+	consensusBytes := pubkey.Address().Bytes()
+
+	encode := func(prefix string, addressBytes []byte) string {
+		address, err := bech32.ConvertAndEncode(prefix, addressBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return address
+	}
+
+	consensusPubkey, err := codectypes.NewAnyWithValue(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validators := []stakingtypes.Validator{{
+		OperatorAddress: sdk.ValAddress(operatorBytes).String(),
+		ConsensusPubkey: consensusPubkey,
+	}}
+
+	var fetched int
+	getValidators := func() ([]stakingtypes.Validator, error) {
+		fetched++
+		return validators, nil
+	}
+
+	tests := []struct {
+		name      string
+		address   string
+		consensus []byte
+		fetched   int
+		err       string
+	}{
+		{name: "account", address: encode("persistence", operatorBytes)},
+		{name: "validator operator", address: encode("persistencevaloper", operatorBytes)},
+		{name: "bech32 consensus", address: encode("persistencevalcons", consensusBytes), consensus: consensusBytes, fetched: 1},
+		{name: "hex consensus", address: strings.ToUpper(hex.EncodeToString(consensusBytes)), consensus: consensusBytes, fetched: 1},
+		{name: "unknown consensus", address: encode("persistencevalcons", operatorBytes), fetched: 1, err: "no validator"},
+		{name: "other chain", address: encode("cosmos", operatorBytes), err: "unexpected bech32 prefix"},
+		{name: "garbage", address: "not-an-address", err: "not a bech32 or a hex consensus address"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fetched = 0
+
+			resolved, err := resolveAddress(test.address, getValidators)
+			if fetched != test.fetched {
+				t.Errorf("validators fetched %d times, expected %d", fetched, test.fetched)
+			}
+
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, expected %q", err, test.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("could not resolve: %s", err)
+			}
+
+			if !bytes.Equal(resolved.Account, operatorBytes) || !bytes.Equal(resolved.Validator, operatorBytes) {
+				t.Errorf("resolved to %X and %X, expected %X", resolved.Account.Bytes(), resolved.Validator.Bytes(), operatorBytes)
+			}
+
+			if !bytes.Equal(resolved.Consensus, test.consensus) {
+				t.Errorf("consensus address is %X, expected %X", resolved.Consensus.Bytes(), test.consensus)
+			}
+		})
+	}
+
+	t.Run("validators not available", func(t *testing.T) {
+		_, err := resolveAddress(encode("persistencevalcons", consensusBytes), func() ([]stakingtypes.Validator, error) {
+			return nil, errors.New("unavailable")
+		})
+		if err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
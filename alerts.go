@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	alertTypeJailed         = "jailed"
+	alertTypeMissedBlocks   = "missed-blocks"
+	alertTypeInactive       = "inactive"
+	alertTypeLowBalance     = "low-balance"
+	alertTypeGovVoteMissing = "gov-vote-missing"
+)
+
+type firingAlert struct {
+	Summary  string
+	StartsAt time.Time
+	// EndsAt is set once the alert is resolved, until that's notified.
+	EndsAt *time.Time
+	// Notified is set once its firing was sent to any of the notifiers.
+	Notified bool
+}
+
+// AlertEngine evaluates the alert rules from the config in the background
+// and notifies when an alert starts firing and when it's resolved. An alert
+// that keeps firing is only notified about once, and a notification that
+// could not be sent to any of the notifiers is retried on the next
+// evaluation.
+type AlertEngine struct {
+	mutex sync.RWMutex
+
+	notifiers map[string]AlertNotifier
+	firing    map[string]*firingAlert
+	// evaluated tells for every rule whether its last evaluation succeeded.
+	evaluated map[string]bool
+}
+
+var alertEngine = NewAlertEngine(nil)
+
+func NewAlertEngine(notifiers []AlertNotifierConfig) *AlertEngine {
+	engine := &AlertEngine{
+		notifiers: make(map[string]AlertNotifier, len(notifiers)),
+		firing:    map[string]*firingAlert{},
+		evaluated: map[string]bool{},
+	}
+
+	for _, notifier := range notifiers {
+		engine.notifiers[notifier.Name] = NewAlertNotifier(notifier)
+	}
+
+	return engine
+}
+
+func (e *AlertEngine) Evaluate(grpcConn NodeConn) error {
+	scrape := NewScrape(grpcConn)
+
+	for _, rule := range AlertRules {
+		firing, summary, err := evaluateAlertRule(scrape, rule)
+
+		e.mutex.Lock()
+		e.evaluated[rule.Name] = err == nil
+		if err != nil {
+			e.mutex.Unlock()
+			log.Error().
+				Str("alert", rule.Name).
+				Str("address", rule.Address).
+				Err(err).
+				Msg("Could not evaluate alert rule")
+			continue
+		}
+
+		now := time.Now()
+		current, wasFiring := e.firing[rule.Name]
+		switch {
+		case firing && !wasFiring:
+			current = &firingAlert{Summary: summary, StartsAt: now}
+			e.firing[rule.Name] = current
+		case firing:
+			// If it fires again before its resolution could be sent, it
+			// never stopped firing for whoever got the firing notification.
+			current.Summary = summary
+			current.EndsAt = nil
+		case wasFiring && !current.Notified:
+			// Its firing was never sent, so there's nothing to resolve.
+			delete(e.firing, rule.Name)
+			current = nil
+		case wasFiring && current.EndsAt == nil:
+			current.EndsAt = &now
+		}
+
+		notification := AlertNotification{
+			Rule:    rule.Name,
+			Type:    rule.Type,
+			Address: rule.Address,
+			Summary: summary,
+			ChainID: ChainID,
+		}
+
+		if current != nil {
+			notification.StartsAt = current.StartsAt
+			if !current.Notified {
+				notification.Status = alertStatusFiring
+			} else if current.EndsAt != nil {
+				notification.Status = alertStatusResolved
+				notification.EndsAt = current.EndsAt
+			}
+		}
+		e.mutex.Unlock()
+
+		if notification.Status == "" || !e.notify(rule, notification) {
+			continue
+		}
+
+		e.mutex.Lock()
+		if notification.Status == alertStatusFiring {
+			current.Notified = true
+		} else if e.firing[rule.Name] == current && current.EndsAt != nil {
+			delete(e.firing, rule.Name)
+		}
+		e.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// notify sends the notification to the notifiers of the rule, returning
+// whether any of them got it.
+func (e *AlertEngine) notify(rule AlertRule, notification AlertNotification) bool {
+	names := rule.Notifiers
+	if len(names) == 0 {
+		for name := range e.notifiers {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+	}
+
+	log.Info().
+		Str("alert", rule.Name).
+		Str("status", notification.Status).
+		Str("summary", notification.Summary).
+		Msg("Sending alert notification")
+
+	// Without any notifiers there's nothing to retry.
+	sent := len(names) == 0
+	for _, name := range names {
+		ctx, cancel := context.WithTimeout(context.Background(), notifierTimeout)
+		err := e.notifiers[name].Notify(ctx, notification)
+		cancel()
+
+		if err != nil {
+			log.Error().
+				Str("alert", rule.Name).
+				Str("notifier", name).
+				Err(err).
+				Msg("Could not send alert notification")
+			continue
+		}
+
+		sent = true
+	}
+
+	if !sent {
+		log.Warn().
+			Str("alert", rule.Name).
+			Str("status", notification.Status).
+			Msg("Could not send alert notification to any notifier, retrying on the next evaluation")
+	}
+
+	return sent
+}
+
+// evaluateAlertRule returns whether the alert is firing, with a summary of
+// the current state either way.
+func evaluateAlertRule(scrape *Scrape, rule AlertRule) (bool, string, error) {
+	resolvedAddress, err := resolveAddress(rule.Address, scrape.Validators)
+	if err != nil {
+		return false, "", err
+	}
+
+	switch rule.Type {
+	case alertTypeJailed:
+		validator, err := getAlertValidator(scrape, resolvedAddress)
+		if err != nil {
+			return false, "", err
+		}
+
+		if validator.Jailed {
+			return true, fmt.Sprintf("validator %s is jailed", validator.Description.Moniker), nil
+		}
+
+		return false, fmt.Sprintf("validator %s is not jailed", validator.Description.Moniker), nil
+	case alertTypeMissedBlocks:
+		return evaluateMissedBlocksRule(scrape, rule, resolvedAddress)
+	case alertTypeInactive:
+		return evaluateInactiveRule(scrape, rule, resolvedAddress)
+	case alertTypeLowBalance:
+		return evaluateLowBalanceRule(scrape, rule, resolvedAddress)
+	case alertTypeGovVoteMissing:
+		return evaluateGovVoteMissingRule(scrape, rule, resolvedAddress)
+	default:
+		return false, "", fmt.Errorf("unsupported alert type %s", rule.Type)
+	}
+}
+
+func getAlertValidator(scrape *Scrape, resolvedAddress *ResolvedAddress) (stakingtypes.Validator, error) {
+	stakingClient := stakingtypes.NewQueryClient(scrape.GrpcConn)
+	response, err := stakingClient.Validator(
+		scrape.Context(),
+		&stakingtypes.QueryValidatorRequest{ValidatorAddr: resolvedAddress.Validator.String()},
+	)
+	if err != nil {
+		return stakingtypes.Validator{}, err
+	}
+
+	return response.Validator, nil
+}
+
+func evaluateMissedBlocksRule(scrape *Scrape, rule AlertRule, resolvedAddress *ResolvedAddress) (bool, string, error) {
+	validator, err := getAlertValidator(scrape, resolvedAddress)
+	if err != nil {
+		return false, "", err
+	}
+
+	moniker := validator.Description.Moniker
+
+	// Validators out of the active set don't sign, so their counter doesn't
+	// change. The inactive rule is the one for them.
+	if validator.Status != stakingtypes.Bonded {
+		return false, fmt.Sprintf("validator %s is not in the active set", moniker), nil
+	}
+
+	consAddr := getValidatorConsAddr(validator, log)
+	if consAddr == nil {
+		return false, "", fmt.Errorf("could not get the consensus address of %s", validator.OperatorAddress)
+	}
+
+	slashingClient := slashingtypes.NewQueryClient(scrape.GrpcConn)
+	response, err := slashingClient.SigningInfo(
+		scrape.Context(),
+		&slashingtypes.QuerySigningInfoRequest{ConsAddress: consAddr.String()},
+	)
+	if err != nil {
+		return false, "", err
+	}
+
+	missed := response.ValSigningInfo.MissedBlocksCounter
+	summary := fmt.Sprintf("validator %s missed %d blocks in the signing window, the threshold is %g", moniker, missed, rule.Threshold)
+
+	return float64(missed) > rule.Threshold, summary, nil
+}
+
+func evaluateInactiveRule(scrape *Scrape, rule AlertRule, resolvedAddress *ResolvedAddress) (bool, string, error) {
+	validators, err := scrape.Validators()
+	if err != nil {
+		return false, "", err
+	}
+
+	stakingClient := stakingtypes.NewQueryClient(scrape.GrpcConn)
+	paramsResponse, err := stakingClient.Params(scrape.Context(), &stakingtypes.QueryParamsRequest{})
+	if err != nil {
+		return false, "", err
+	}
+
+	maxValidators := int(paramsResponse.Params.MaxValidators)
+
+	// The jailed validators can't be in the active set, so they don't take
+	// any place in it either.
+	var rank int
+	for _, validator := range validators {
+		if validator.Jailed {
+			continue
+		}
+
+		rank++
+
+		if validator.OperatorAddress != resolvedAddress.Validator.String() {
+			continue
+		}
+
+		moniker := validator.Description.Moniker
+		if validator.Status != stakingtypes.Bonded {
+			return true, fmt.Sprintf("validator %s is not in the active set (%s)", moniker, validator.Status.String()), nil
+		}
+
+		summary := fmt.Sprintf("validator %s is ranked %d of the %d active places", moniker, rank, maxValidators)
+		return rule.Threshold > 0 && float64(rank) > float64(maxValidators)-rule.Threshold, summary, nil
+	}
+
+	for _, validator := range validators {
+		if validator.OperatorAddress == resolvedAddress.Validator.String() {
+			return true, fmt.Sprintf("validator %s is jailed and not in the active set", validator.Description.Moniker), nil
+		}
+	}
+
+	return false, "", fmt.Errorf("validator %s not found", resolvedAddress.Validator.String())
+}
+
+func evaluateLowBalanceRule(scrape *Scrape, rule AlertRule, resolvedAddress *ResolvedAddress) (bool, string, error) {
+	denom := rule.Denom
+	if denom == "" {
+		denom = BondDenom
+	}
+
+	address := resolvedAddress.Account.String()
+
+	bankClient := banktypes.NewQueryClient(scrape.GrpcConn)
+	response, err := bankClient.Balance(
+		scrape.Context(),
+		&banktypes.QueryBalanceRequest{Address: address, Denom: denom},
+	)
+	if err != nil {
+		return false, "", err
+	}
+
+	balance, _ := new(big.Float).SetInt(response.Balance.Amount.BigInt()).Float64()
+	balance = getDisplayAmount(denom, balance)
+
+	displayDenom := denom
+	if denom == BondDenom {
+		displayDenom = Denom
+	}
+
+	summary := fmt.Sprintf("wallet %s has %g %s, the threshold is %g", address, balance, displayDenom, rule.Threshold)
+
+	return balance < rule.Threshold, summary, nil
+}
+
+func evaluateGovVoteMissingRule(scrape *Scrape, rule AlertRule, resolvedAddress *ResolvedAddress) (bool, string, error) {
+	voter := resolvedAddress.Account.String()
+
+	proposals, err := getVotingProposals(scrape.Context(), scrape.GrpcConn, "")
+	if err != nil {
+		return false, "", err
+	}
+
+	votedProposals, err := getVotingProposals(scrape.Context(), scrape.GrpcConn, voter)
+	if err != nil {
+		return false, "", err
+	}
+
+	voted := make(map[uint64]bool, len(votedProposals))
+	for _, proposal := range votedProposals {
+		voted[proposal.Id] = true
+	}
+
+	var missing []string
+	for _, proposal := range proposals {
+		if voted[proposal.Id] {
+			continue
+		}
+
+		// With a threshold, only the proposals ending within that many
+		// hours are alerted about.
+		if rule.Threshold > 0 && proposal.VotingEndTime != nil &&
+			time.Until(*proposal.VotingEndTime).Hours() > rule.Threshold {
+			continue
+		}
+
+		missing = append(missing, fmt.Sprintf("#%d", proposal.Id))
+	}
+
+	if len(missing) > 0 {
+		return true, fmt.Sprintf("%s has not voted on the proposals %s", voter, strings.Join(missing, ", ")), nil
+	}
+
+	return false, fmt.Sprintf("%s has voted on all the proposals in the voting period", voter), nil
+}
+
+func AlertsHandler(w http.ResponseWriter, r *http.Request) {
+	requestStart := time.Now()
+
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	alertFiringGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_alert_firing",
+			Help:        "1 if the alert rule from the config is firing, 0 if not",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"alert", "type", "address"},
+	)
+
+	alertEvaluationSuccessGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_alert_evaluation_success",
+			Help:        "1 if the last evaluation of the alert rule succeeded, 0 if not",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"alert"},
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(alertFiringGauge)
+	registry.MustRegister(alertEvaluationSuccessGauge)
+
+	alertEngine.mutex.RLock()
+	for _, rule := range AlertRules {
+		succeeded, ok := alertEngine.evaluated[rule.Name]
+		if !ok {
+			continue
+		}
+
+		var evaluationSuccess float64
+		if succeeded {
+			evaluationSuccess = 1
+		}
+		alertEvaluationSuccessGauge.With(prometheus.Labels{
+			"alert": rule.Name,
+		}).Set(evaluationSuccess)
+
+		var firing float64
+		if alert, ok := alertEngine.firing[rule.Name]; ok && alert.EndsAt == nil {
+			firing = 1
+		}
+		alertFiringGauge.With(prometheus.Labels{
+			"alert":   rule.Name,
+			"type":    rule.Type,
+			"address": rule.Address,
+		}).Set(firing)
+	}
+	alertEngine.mutex.RUnlock()
+
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+	sublogger.Info().
+		Str("method", "GET").
+		Str("endpoint", "/metrics/alerts").
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// balanceConn answers the balance queries with the balance set. The other
+// queries fail, so the scrapes go to the latest height.
+type balanceConn struct {
+	balance sdk.Coin
+}
+
+func (c *balanceConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	switch reply := reply.(type) {
+	case *banktypes.QueryBalanceResponse:
+		reply.Balance = &c.balance
+	default:
+		return fmt.Errorf("unexpected query %s", method)
+	}
+
+	return nil
+}
+
+func (c *balanceConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, fmt.Errorf("unexpected stream %s", method)
+}
+
+func (c *balanceConn) Close() error {
+	return nil
+}
+
+// recordingNotifier records the notifications it's asked to send, failing
+// to send them while fail is set.
+type recordingNotifier struct {
+	fail          bool
+	notifications []AlertNotification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification AlertNotification) error {
+	n.notifications = append(n.notifications, notification)
+	if n.fail {
+		return errors.New("notifier unavailable")
+	}
+
+	return nil
+}
+
+func TestAlertEngineEvaluate(t *testing.T) {
+	previousRules, previousPrefix := AlertRules, AccountPrefix
+	previousBondDenom, previousDenom, previousCoefficient := BondDenom, Denom, DenomCoefficient
+	AccountPrefix = sdk.GetConfig().GetBech32AccountAddrPrefix()
+	BondDenom, Denom, DenomCoefficient = "uatom", "atom", 1000000
+	t.Cleanup(func() {
+		AlertRules, AccountPrefix = previousRules, previousPrefix
+		BondDenom, Denom, DenomCoefficient = previousBondDenom, previousDenom, previousCoefficient
+	})
+
+	rule := AlertRule{
+		Name:      "low-balance",
+		Type:      alertTypeLowBalance,
+		Address:   sdk.AccAddress([]byte("wallet______________")).String(),
+		Threshold: 10,
+	}
+	AlertRules = []AlertRule{rule}
+
+	// A step is an evaluation with the balance in atom, and whether the
+	// notifier fails. It's expected to send the notification with the
+	// status, if any, and to leave the alert firing or not.
+	type step struct {
+		balance int64
+		fail    bool
+		status  string
+		firing  bool
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "firing",
+			steps: []step{
+				{balance: 5, status: alertStatusFiring, firing: true},
+			},
+		},
+		{
+			name: "still firing",
+			steps: []step{
+				{balance: 5, status: alertStatusFiring, firing: true},
+				{balance: 4, firing: true},
+			},
+		},
+		{
+			name: "resolved",
+			steps: []step{
+				{balance: 5, status: alertStatusFiring, firing: true},
+				{balance: 20, status: alertStatusResolved},
+				{balance: 20},
+			},
+		},
+		{
+			name: "send failed then retried",
+			steps: []step{
+				{balance: 5, fail: true, status: alertStatusFiring, firing: true},
+				{balance: 5, status: alertStatusFiring, firing: true},
+				{balance: 5, firing: true},
+			},
+		},
+		{
+			// Its firing was never sent, so neither is its resolution.
+			name: "resolved before the firing is sent",
+			steps: []step{
+				{balance: 5, fail: true, status: alertStatusFiring, firing: true},
+				{balance: 20},
+			},
+		},
+		{
+			// It never stopped firing for whoever got the firing
+			// notification, so it's not notified again.
+			name: "firing again before the resolution is sent",
+			steps: []step{
+				{balance: 5, status: alertStatusFiring, firing: true},
+				{balance: 20, fail: true, status: alertStatusResolved},
+				{balance: 5, firing: true},
+				{balance: 20, status: alertStatusResolved},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notifier := &recordingNotifier{}
+			engine := NewAlertEngine(nil)
+			engine.notifiers["recorder"] = notifier
+			conn := &balanceConn{}
+
+			var notifications []AlertNotification
+			for index, step := range test.steps {
+				conn.balance = sdk.NewCoin("uatom", sdkmath.NewInt(step.balance*1000000))
+				notifier.fail = step.fail
+				notifier.notifications = nil
+
+				if err := engine.Evaluate(conn); err != nil {
+					t.Fatalf("step %d: could not evaluate: %s", index, err)
+				}
+
+				if !engine.evaluated[rule.Name] {
+					t.Errorf("step %d: the rule was not evaluated", index)
+				}
+
+				var status string
+				switch len(notifier.notifications) {
+				case 0:
+				case 1:
+					status = notifier.notifications[0].Status
+					notifications = append(notifications, notifier.notifications[0])
+				default:
+					t.Fatalf("step %d: got %d notifications, expected at most one", index, len(notifier.notifications))
+				}
+
+				if status != step.status {
+					t.Errorf("step %d: got notification %q, expected %q", index, status, step.status)
+				}
+
+				alert, ok := engine.firing[rule.Name]
+				if firing := ok && alert.EndsAt == nil; firing != step.firing {
+					t.Errorf("step %d: got firing %t, expected %t", index, firing, step.firing)
+				}
+			}
+
+			// All the notifications are about the same alert, which
+			// started firing once.
+			for _, notification := range notifications {
+				if !notification.StartsAt.Equal(notifications[0].StartsAt) {
+					t.Errorf("got notification starting at %s, expected %s", notification.StartsAt, notifications[0].StartsAt)
+				}
+
+				if (notification.Status == alertStatusResolved) != (notification.EndsAt != nil) {
+					t.Errorf("got %s notification ending at %v", notification.Status, notification.EndsAt)
+				}
+			}
+
+			// The resolved alerts that were notified are forgotten.
+			if last := test.steps[len(test.steps)-1]; !last.firing && !last.fail {
+				if _, ok := engine.firing[rule.Name]; ok {
+					t.Errorf("got the resolved alert still kept")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	BackfillFromHeight int64
+	BackfillToHeight   int64
+	BackfillStep       int64
+	BackfillOutput     string
+	BackfillValidators []string
+	BackfillWallets    []string
+)
+
+// Backfill merges the metric families gathered at all the heights, as
+// OpenMetrics requires all the samples of a family to be written together.
+type Backfill struct {
+	families map[string]*dto.MetricFamily
+}
+
+func NewBackfill() *Backfill {
+	return &Backfill{families: map[string]*dto.MetricFamily{}}
+}
+
+// Add appends the metrics gathered at a single height, timestamped with the
+// time of its block.
+func (b *Backfill) Add(families []*dto.MetricFamily, blockTime time.Time) {
+	timestamp := blockTime.UnixMilli()
+
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			metric.TimestampMs = &timestamp
+		}
+
+		merged, ok := b.families[family.GetName()]
+		if !ok {
+			b.families[family.GetName()] = family
+			continue
+		}
+
+		merged.Metric = append(merged.Metric, family.Metric...)
+	}
+}
+
+// Write writes all the families in the OpenMetrics format, with the samples
+// of every series one after another in the height order.
+func (b *Backfill) Write(w io.Writer) error {
+	names := make([]string, 0, len(b.families))
+	for name := range b.families {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		family := b.families[name]
+		sort.SliceStable(family.Metric, func(i, j int) bool {
+			return getLabelsKey(family.Metric[i]) < getLabelsKey(family.Metric[j])
+		})
+
+		if _, err := expfmt.MetricFamilyToOpenMetrics(w, family); err != nil {
+			return err
+		}
+	}
+
+	_, err := expfmt.FinalizeOpenMetrics(w)
+	return err
+}
+
+func getLabelsKey(metric *dto.Metric) string {
+	pairs := make([]string, len(metric.Label))
+	for index, label := range metric.Label {
+		pairs[index] = label.GetName() + "=" + label.GetValue()
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Export the validator and wallet metrics of past heights as OpenMetrics",
+	Long: "Query the validators and wallets from the config and the flags at every step of the height range " +
+		"and write their metrics as OpenMetrics, timestamped with the block times, " +
+		"for promtool tsdb create-blocks-from openmetrics. The node must be an archive one for the old heights.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		setLogger()
+
+		if err := loadConfigSections(); err != nil {
+			log.Fatal().Err(err).Msg("Could not parse config")
+		}
+
+		setBechConfig()
+
+		validatorTargets := append(getTargets(BackfillValidators), ValidatorTargets...)
+		walletTargets := append(getTargets(BackfillWallets), WalletTargets...)
+		if len(validatorTargets) == 0 && len(walletTargets) == 0 {
+			log.Fatal().Msg("No validators or wallets to backfill, set them in the config or with --validator and --wallet")
+		}
+
+		if BackfillStep <= 0 {
+			log.Fatal().Int64("step", BackfillStep).Msg("Step must be positive")
+		}
+
+		checkMaxConcurrentTargets()
+
+		grpcConn := dialNode()
+		defer grpcConn.Close()
+
+		setChainID()
+		capabilities = probeCapabilities(grpcConn)
+
+		setDenom(grpcConn)
+
+		toHeight := BackfillToHeight
+		if toHeight == 0 {
+			latestHeight, err := getLatestHeight(context.Background(), grpcConn)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Could not get the latest height, set it with --to-height")
+			}
+
+			toHeight = latestHeight
+		}
+
+		if BackfillFromHeight <= 0 || BackfillFromHeight > toHeight {
+			log.Fatal().
+				Int64("from-height", BackfillFromHeight).
+				Int64("to-height", toHeight).
+				Msg("Invalid height range")
+		}
+
+		file, err := os.Create(BackfillOutput)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Could not create the output file")
+		}
+		defer file.Close()
+
+		backfill := NewBackfill()
+
+		for height := BackfillFromHeight; height <= toHeight; height += BackfillStep {
+			heightStart := time.Now()
+			sublogger := log.With().
+				Int64("height", height).
+				Logger()
+
+			scrape := &Scrape{GrpcConn: grpcConn, Height: height}
+
+			blockTime, err := getBlockTime(scrape.Context(), grpcConn, height)
+			if err != nil {
+				sublogger.Error().Err(err).Msg("Could not get block time, skipping the height")
+				continue
+			}
+
+			// The maturing and the vesting amounts are computed at the time
+			// of the block, not the current one.
+			scrape.BlockTime = blockTime
+
+			var gatherers prometheus.Gatherers
+			gatherers = append(gatherers, collectTargets(validatorTargets, collectValidatorMetrics, scrape, sublogger)...)
+			gatherers = append(gatherers, collectTargets(walletTargets, collectWalletMetrics, scrape, sublogger)...)
+
+			families, err := gatherers.Gather()
+			if err != nil {
+				sublogger.Warn().Err(err).Msg("Could not gather some of the metrics")
+			}
+
+			backfill.Add(families, blockTime)
+
+			sublogger.Info().
+				Time("block-time", blockTime).
+				Float64("request-time", time.Since(heightStart).Seconds()).
+				Msg("Height processed")
+		}
+
+		writer := bufio.NewWriter(file)
+		if err := backfill.Write(writer); err != nil {
+			log.Fatal().Err(err).Msg("Could not write metrics")
+		}
+
+		if err := writer.Flush(); err != nil {
+			log.Fatal().Err(err).Msg("Could not write metrics")
+		}
+
+		log.Info().
+			Str("output", BackfillOutput).
+			Msg("Backfill finished, import it with promtool tsdb create-blocks-from openmetrics")
+	},
+}
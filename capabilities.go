@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Query services of the modules the exporter queries.
+const (
+	authQueryService         = "cosmos.auth.v1beta1.Query"
+	bankQueryService         = "cosmos.bank.v1beta1.Query"
+	distributionQueryService = "cosmos.distribution.v1beta1.Query"
+	mintQueryService         = "cosmos.mint.v1beta1.Query"
+	slashingQueryService     = "cosmos.slashing.v1beta1.Query"
+	stakingQueryService      = "cosmos.staking.v1beta1.Query"
+)
+
+var knownQueryServices = []string{
+	authQueryService,
+	bankQueryService,
+	distributionQueryService,
+	mintQueryService,
+	slashingQueryService,
+	stakingQueryService,
+}
+
+// Capabilities are the query services the node exposes, probed once at
+// startup through the gRPC server reflection.
+type Capabilities struct {
+	// Probed is false if the node doesn't support the server reflection, in
+	// which case all the services are assumed to be available.
+	Probed   bool
+	Services map[string]bool
+}
+
+var capabilities = &Capabilities{}
+
+// probeCapabilities lists the services of the node. It never fails, a node
+// without the server reflection is assumed to expose everything, as before.
+func probeCapabilities(grpcConn NodeConn) *Capabilities {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	services, err := listServices(ctx, grpcConn)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Msg("Could not list the node services, assuming all modules are available")
+		return &Capabilities{}
+	}
+
+	probed := &Capabilities{
+		Probed:   true,
+		Services: make(map[string]bool, len(services)),
+	}
+	for _, service := range services {
+		probed.Services[service] = true
+	}
+
+	for _, service := range knownQueryServices {
+		if !probed.Services[service] {
+			log.Warn().
+				Str("module", getServiceModule(service)).
+				Msg("Module is not available on the node, its metrics are not exported")
+		}
+	}
+
+	return probed
+}
+
+// HasService returns whether the node exposes the service.
+func (c *Capabilities) HasService(service string) bool {
+	if !c.Probed {
+		return true
+	}
+
+	return c.Services[service]
+}
+
+// getServiceModule returns the module of a Cosmos SDK service, for example
+// "mint" for "cosmos.mint.v1beta1.Query".
+func getServiceModule(service string) string {
+	parts := strings.Split(service, ".")
+	if len(parts) < 3 {
+		return service
+	}
+
+	return parts[len(parts)-3]
+}
+
+func CapabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	requestStart := time.Now()
+
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	capabilitiesProbedGauge := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_exporter_capabilities_probed",
+			Help:        "1 if the node services were listed through the gRPC server reflection, 0 if all of them are assumed to be available",
+			ConstLabels: ConstLabels,
+		},
+	)
+
+	moduleAvailableGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_exporter_module_available",
+			Help:        "1 if the node exposes the query service of a module the exporter queries, 0 if not",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"module", "service"},
+	)
+
+	serviceGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_exporter_node_service",
+			Help:        "A gRPC service the node exposes, always 1",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"service"},
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(capabilitiesProbedGauge)
+	registry.MustRegister(moduleAvailableGauge)
+	registry.MustRegister(serviceGauge)
+
+	if capabilities.Probed {
+		capabilitiesProbedGauge.Set(1)
+	} else {
+		capabilitiesProbedGauge.Set(0)
+	}
+
+	for _, service := range knownQueryServices {
+		var available float64
+		if capabilities.HasService(service) {
+			available = 1
+		} else {
+			available = 0
+		}
+
+		moduleAvailableGauge.With(prometheus.Labels{
+			"module":  getServiceModule(service),
+			"service": service,
+		}).Set(available)
+	}
+
+	for service := range capabilities.Services {
+		serviceGauge.With(prometheus.Labels{
+			"service": service,
+		}).Set(1)
+	}
+
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+	sublogger.Info().
+		Str("method", "GET").
+		Str("endpoint", "/metrics/capabilities").
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	vestingexported "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Components subtracted from the total supply to get the circulating supply.
+const (
+	circulatingSupplyModuleAccounts    = "module_accounts"
+	circulatingSupplyCommunityPool     = "community_pool"
+	circulatingSupplyVestingLocked     = "vesting_locked"
+	circulatingSupplyExcludedAddresses = "excluded_addresses"
+)
+
+// CirculatingSupply keeps the circulating supply and its subtracted
+// components, refreshed in the background as getting the locked vesting
+// amounts requires walking through all the accounts.
+type CirculatingSupply struct {
+	mutex sync.RWMutex
+
+	circulating map[string]float64
+	excluded    map[string]map[string]float64
+	// skipped counts the accounts of unknown types by type URL, which are
+	// left out of the computation.
+	skipped     map[string]int
+	refreshedAt time.Time
+	height      int64
+}
+
+var circulatingSupply = &CirculatingSupply{}
+
+func (c *CirculatingSupply) Refresh(grpcConn NodeConn) error {
+	scrape := NewScrape(grpcConn)
+	ctx := scrape.Context()
+	now := time.Now()
+
+	bankClient := banktypes.NewQueryClient(grpcConn)
+	supplyResponse, err := bankClient.TotalSupply(ctx, &banktypes.QueryTotalSupplyRequest{})
+	if err != nil {
+		return err
+	}
+
+	excluded := map[string]sdk.Coins{}
+	excludedAddresses := make(map[string]bool, len(CirculatingSupplyExcludedAddresses))
+
+	skipped := map[string]int{}
+	moduleAccounts, err := getModuleAccounts(ctx, grpcConn, skipped)
+	if err != nil {
+		return err
+	}
+
+	includedModules := make(map[string]bool, len(CirculatingSupplyIncludedModules))
+	for _, module := range CirculatingSupplyIncludedModules {
+		includedModules[module] = true
+	}
+
+	var communityPool sdk.Coins
+	if capabilities.HasService(distributionQueryService) {
+		distributionClient := distributiontypes.NewQueryClient(grpcConn)
+		communityPoolResponse, err := distributionClient.CommunityPool(ctx, &distributiontypes.QueryCommunityPoolRequest{})
+		if err != nil {
+			return err
+		}
+
+		communityPool, _ = communityPoolResponse.Pool.TruncateDecimal()
+		excluded[circulatingSupplyCommunityPool] = communityPool
+	}
+
+	for name, account := range moduleAccounts {
+		address := account.GetAddress().String()
+		excludedAddresses[address] = true
+
+		if includedModules[name] {
+			continue
+		}
+
+		balances, err := getAllBalances(ctx, grpcConn, address)
+		if err != nil {
+			return err
+		}
+
+		// The community pool is held by the distribution module account,
+		// it's reported separately so it should not be subtracted twice.
+		if name == distributiontypes.ModuleName {
+			balances, _ = balances.SafeSub(communityPool...)
+		}
+
+		excluded[circulatingSupplyModuleAccounts] = excluded[circulatingSupplyModuleAccounts].Add(balances...)
+	}
+
+	for _, address := range CirculatingSupplyExcludedAddresses {
+		if excludedAddresses[address] {
+			continue
+		}
+		excludedAddresses[address] = true
+
+		balances, err := getAllBalances(ctx, grpcConn, address)
+		if err != nil {
+			return err
+		}
+
+		excluded[circulatingSupplyExcludedAddresses] = excluded[circulatingSupplyExcludedAddresses].Add(balances...)
+	}
+
+	accounts, err := getAllAccounts(ctx, grpcConn, skipped)
+	if err != nil {
+		return err
+	}
+
+	for typeURL, count := range skipped {
+		log.Warn().
+			Str("type", typeURL).
+			Int("accounts", count).
+			Msg("Could not unpack accounts of an unknown type, leaving them out of the circulating supply")
+	}
+
+	for _, account := range accounts {
+		vestingAccount, ok := account.(vestingexported.VestingAccount)
+		if !ok || excludedAddresses[account.GetAddress().String()] {
+			continue
+		}
+
+		excluded[circulatingSupplyVestingLocked] = excluded[circulatingSupplyVestingLocked].Add(vestingAccount.GetVestingCoins(now)...)
+	}
+
+	circulating := make(map[string]float64, len(supplyResponse.Supply))
+	excludedByComponent := make(map[string]map[string]float64, len(excluded))
+
+	for _, coin := range supplyResponse.Supply {
+		value, _ := new(big.Float).SetInt(coin.Amount.BigInt()).Float64()
+		circulating[coin.Denom] = value
+	}
+
+	for component, coins := range excluded {
+		excludedByComponent[component] = make(map[string]float64, len(coins))
+		for _, coin := range coins {
+			if _, ok := circulating[coin.Denom]; !ok {
+				continue
+			}
+
+			value, _ := new(big.Float).SetInt(coin.Amount.BigInt()).Float64()
+			excludedByComponent[component][coin.Denom] = value
+			circulating[coin.Denom] -= value
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.circulating = circulating
+	c.excluded = excludedByComponent
+	c.skipped = skipped
+	c.refreshedAt = now
+	c.height = scrape.Height
+
+	return nil
+}
+
+// Export sets the gauges from the last refresh. It does nothing if the
+// circulating supply has not been computed yet.
+func (c *CirculatingSupply) Export(
+	circulatingGauge *prometheus.GaugeVec,
+	excludedGauge *prometheus.GaugeVec,
+	skippedGauge *prometheus.GaugeVec,
+	snapshotHeightGauge *prometheus.GaugeVec,
+) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.refreshedAt.IsZero() {
+		return
+	}
+
+	for denom, value := range c.circulating {
+		circulatingGauge.With(prometheus.Labels{
+			"denom": denom,
+		}).Set(value / DenomCoefficient)
+	}
+
+	for component, values := range c.excluded {
+		for denom, value := range values {
+			excludedGauge.With(prometheus.Labels{
+				"denom":     denom,
+				"component": component,
+			}).Set(value / DenomCoefficient)
+		}
+	}
+
+	for typeURL, count := range c.skipped {
+		skippedGauge.With(prometheus.Labels{
+			"type": typeURL,
+		}).Set(float64(count))
+	}
+
+	setSnapshotHeight(snapshotHeightGauge, "circulating-supply", c.height)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	sdkmath "cosmossdk.io/math"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+)
+
+// supplyConn answers the queries of the circulating supply refresh from the
+// accounts and the balances it holds. The height query fails, so the
+// refresh goes to the latest height.
+type supplyConn struct {
+	supply         sdk.Coins
+	communityPool  sdk.DecCoins
+	moduleAccounts []*codectypes.Any
+	accounts       []*codectypes.Any
+	balances       map[string]sdk.Coins
+}
+
+func (c *supplyConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	switch reply := reply.(type) {
+	case *banktypes.QueryTotalSupplyResponse:
+		reply.Supply = c.supply
+	case *distributiontypes.QueryCommunityPoolResponse:
+		reply.Pool = c.communityPool
+	case *authtypes.QueryModuleAccountsResponse:
+		reply.Accounts = c.moduleAccounts
+	case *authtypes.QueryAccountsResponse:
+		reply.Accounts = c.accounts
+	case *banktypes.QueryAllBalancesResponse:
+		reply.Balances = c.balances[args.(*banktypes.QueryAllBalancesRequest).Address]
+	default:
+		return fmt.Errorf("unexpected query %s", method)
+	}
+
+	return nil
+}
+
+func (c *supplyConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, fmt.Errorf("unexpected stream %s", method)
+}
+
+func (c *supplyConn) Close() error {
+	return nil
+}
+
+func packAccount(t *testing.T, account sdk.AccountI) *codectypes.Any {
+	t.Helper()
+
+	accountAny, err := codectypes.NewAnyWithValue(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return accountAny
+}
+
+func newDelayedVestingAccount(t *testing.T, address sdk.AccAddress, amount string, endTime time.Time) sdk.AccountI {
+	t.Helper()
+
+	coins, err := sdk.ParseCoinsNormalized(amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := vestingtypes.NewDelayedVestingAccount(authtypes.NewBaseAccountWithAddress(address), coins, endTime.Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return account
+}
+
+func TestCirculatingSupplyRefresh(t *testing.T) {
+	previousExcluded, previousIncluded := CirculatingSupplyExcludedAddresses, CirculatingSupplyIncludedModules
+	t.Cleanup(func() {
+		CirculatingSupplyExcludedAddresses, CirculatingSupplyIncludedModules = previousExcluded, previousIncluded
+	})
+
+	distributionAccount := authtypes.NewEmptyModuleAccount(distributiontypes.ModuleName)
+	bondedPoolAccount := authtypes.NewEmptyModuleAccount("bonded_tokens_pool")
+	mintAccount := authtypes.NewEmptyModuleAccount("mint")
+	treasury := sdk.AccAddress([]byte("treasury____________"))
+	vesting := sdk.AccAddress([]byte("vesting_____________"))
+	vested := sdk.AccAddress([]byte("vested______________"))
+
+	conn := &supplyConn{
+		supply:        sdk.NewCoins(sdk.NewInt64Coin("uatom", 1000), sdk.NewInt64Coin("ibc/ABC", 50)),
+		communityPool: sdk.NewDecCoinsFromCoins(sdk.NewInt64Coin("uatom", 60)).Add(sdk.NewDecCoinFromDec("uatom", sdkmath.LegacyMustNewDecFromStr("0.5"))),
+		moduleAccounts: []*codectypes.Any{
+			packAccount(t, distributionAccount),
+			packAccount(t, bondedPoolAccount),
+			packAccount(t, mintAccount),
+		},
+		accounts: []*codectypes.Any{
+			packAccount(t, newDelayedVestingAccount(t, vesting, "100uatom", time.Now().Add(time.Hour))),
+			packAccount(t, newDelayedVestingAccount(t, vested, "70uatom", time.Now().Add(-time.Hour))),
+			packAccount(t, newDelayedVestingAccount(t, treasury, "30uatom", time.Now().Add(time.Hour))),
+			{TypeUrl: "/ibc.applications.interchain_accounts.v1.InterchainAccount", Value: []byte{1}},
+		},
+		balances: map[string]sdk.Coins{
+			distributionAccount.GetAddress().String(): sdk.NewCoins(sdk.NewInt64Coin("uatom", 100)),
+			bondedPoolAccount.GetAddress().String():   sdk.NewCoins(sdk.NewInt64Coin("uatom", 300)),
+			mintAccount.GetAddress().String():         sdk.NewCoins(sdk.NewInt64Coin("uatom", 10), sdk.NewInt64Coin("ibc/ABC", 5)),
+			treasury.String():                         sdk.NewCoins(sdk.NewInt64Coin("uatom", 200)),
+		},
+	}
+
+	tests := []struct {
+		name              string
+		excludedAddresses []string
+		includedModules   []string
+		circulating       map[string]float64
+		excluded          map[string]map[string]float64
+	}{
+		{
+			name:              "all the components",
+			excludedAddresses: []string{treasury.String(), mintAccount.GetAddress().String()},
+			includedModules:   []string{"bonded_tokens_pool"},
+			// The community pool is not subtracted again with the
+			// distribution module account, the mint account not again as
+			// an excluded address, and the vesting treasury not again
+			// with its locked amount.
+			circulating: map[string]float64{"uatom": 1000 - 60 - 50 - 200 - 100, "ibc/ABC": 45},
+			excluded: map[string]map[string]float64{
+				circulatingSupplyCommunityPool:     {"uatom": 60},
+				circulatingSupplyModuleAccounts:    {"uatom": 50, "ibc/ABC": 5},
+				circulatingSupplyExcludedAddresses: {"uatom": 200},
+				circulatingSupplyVestingLocked:     {"uatom": 100},
+			},
+		},
+		{
+			name:        "no excluded addresses or included modules",
+			circulating: map[string]float64{"uatom": 1000 - 60 - 350 - 130, "ibc/ABC": 45},
+			excluded: map[string]map[string]float64{
+				circulatingSupplyCommunityPool:  {"uatom": 60},
+				circulatingSupplyModuleAccounts: {"uatom": 350, "ibc/ABC": 5},
+				circulatingSupplyVestingLocked:  {"uatom": 130},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			CirculatingSupplyExcludedAddresses = test.excludedAddresses
+			CirculatingSupplyIncludedModules = test.includedModules
+
+			supply := &CirculatingSupply{}
+			if err := supply.Refresh(conn); err != nil {
+				t.Fatalf("could not refresh: %s", err)
+			}
+
+			if !reflect.DeepEqual(supply.circulating, test.circulating) {
+				t.Errorf("got circulating supply %v, expected %v", supply.circulating, test.circulating)
+			}
+
+			if !reflect.DeepEqual(supply.excluded, test.excluded) {
+				t.Errorf("got excluded %v, expected %v", supply.excluded, test.excluded)
+			}
+
+			skipped := map[string]int{"/ibc.applications.interchain_accounts.v1.InterchainAccount": 1}
+			if !reflect.DeepEqual(supply.skipped, skipped) {
+				t.Errorf("got skipped accounts %v, expected %v", supply.skipped, skipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/std"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+)
+
+// interfaceRegistry knows the types that can be packed into the Any fields of
+// the query responses, such as the accounts and their pubkeys, or the
+// messages of the transactions.
+var interfaceRegistry = newInterfaceRegistry()
+
+func newInterfaceRegistry() codectypes.InterfaceRegistry {
+	registry := codectypes.NewInterfaceRegistry()
+	std.RegisterInterfaces(registry)
+	authtypes.RegisterInterfaces(registry)
+	vestingtypes.RegisterInterfaces(registry)
+	// The withdrawal messages of the transactions the report goes through.
+	distributiontypes.RegisterInterfaces(registry)
+	authz.RegisterInterfaces(registry)
+	return registry
+}
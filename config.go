@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"text/template"
+
+	"github.com/spf13/viper"
+)
+
+// OperationalWallet is a wallet the exporter keeps an eye on the balance of,
+// such as a relayer, oracle or bot wallet paying for gas.
+type OperationalWallet struct {
+	Address string `mapstructure:"address"`
+	Role    string `mapstructure:"role"`
+	Owner   string `mapstructure:"owner"`
+	// Thresholds are the minimum balances per denom, in the same units as
+	// the exported balances.
+	Thresholds map[string]float64 `mapstructure:"thresholds"`
+}
+
+// Target is a validator or a wallet exported on the aggregated /metrics
+// endpoint, with the labels added to all of its metrics.
+type Target struct {
+	Address string            `mapstructure:"address"`
+	Labels  map[string]string `mapstructure:"labels"`
+}
+
+// Portfolio is a group of wallets exported as totals on the /metrics/portfolio
+// endpoint, with the labels added to all of its metrics.
+type Portfolio struct {
+	Name      string            `mapstructure:"name"`
+	Addresses []string          `mapstructure:"addresses"`
+	Labels    map[string]string `mapstructure:"labels"`
+}
+
+// GenericQuery is a gRPC query of any module, such as a chain-specific one,
+// with the metrics to extract from its response. The request and the
+// response types are resolved through the node's gRPC server reflection.
+type GenericQuery struct {
+	Name    string `mapstructure:"name"`
+	Service string `mapstructure:"service"`
+	Method  string `mapstructure:"method"`
+	// Request is the request message in the protobuf JSON format.
+	Request string               `mapstructure:"request"`
+	Metrics []GenericQueryMetric `mapstructure:"metrics"`
+}
+
+// GenericQueryMetric is a gauge extracted from a generic query response. The
+// paths are dot-separated field names of the response in the protobuf JSON
+// format with the original field names, going through every element of the
+// lists on the way.
+type GenericQueryMetric struct {
+	Name string `mapstructure:"name"`
+	Help string `mapstructure:"help"`
+	// Each is the path to the list with one value per element, the value
+	// and the labels paths are then relative to the element.
+	Each string `mapstructure:"each"`
+	// Value is the path of the metric value. Without it, the metric is set
+	// to 1, to export non-numeric fields such as enums as labels.
+	Value string `mapstructure:"value"`
+	// Labels are the paths of the label values by the label name.
+	Labels map[string]string `mapstructure:"labels"`
+	// Divisor scales the value, for example to convert from the base denom.
+	Divisor float64 `mapstructure:"divisor"`
+}
+
+// PriceSource is where the price of a denom is taken from. The price is of
+// a single display unit, the same units the token amounts are exported in.
+type PriceSource struct {
+	Denom string `mapstructure:"denom"`
+	// Quote is the currency the price is in, usd if not set.
+	Quote string `mapstructure:"quote"`
+	// Provider is either static, http or file.
+	Provider string `mapstructure:"provider"`
+	// Price is the fixed price of the static provider.
+	Price float64 `mapstructure:"price"`
+	// URL is the JSON endpoint the http provider gets the price from.
+	URL string `mapstructure:"url"`
+	// File is the JSON file the file provider reads the price from, kept
+	// up to date by an external job.
+	File string `mapstructure:"file"`
+	// Field is the dot-separated path of the price in the JSON document.
+	// The file provider defaults to the denom.
+	Field string `mapstructure:"field"`
+}
+
+// AlertRule is a condition on a validator or a wallet the exporter checks
+// itself and notifies about, for the setups without an Alertmanager.
+type AlertRule struct {
+	Name string `mapstructure:"name"`
+	// Type is one of jailed, missed-blocks, inactive, low-balance or
+	// gov-vote-missing.
+	Type    string `mapstructure:"type"`
+	Address string `mapstructure:"address"`
+	// Threshold is the missed blocks count for missed-blocks, how many
+	// places above the last active one the validator starts to be alerted
+	// about for inactive, the balance for low-balance, and how many hours
+	// before the end of the voting period to start alerting for
+	// gov-vote-missing.
+	Threshold float64 `mapstructure:"threshold"`
+	// Denom is the denom of the low-balance balance, the bond denom if not set.
+	Denom string `mapstructure:"denom"`
+	// Notifiers are the names of the notifiers to send the alert to, all of
+	// them if not set.
+	Notifiers []string `mapstructure:"notifiers"`
+}
+
+// AlertNotifierConfig is where the alerts are sent to: a generic webhook
+// with a templated body, a Slack incoming webhook or a Telegram chat.
+type AlertNotifierConfig struct {
+	Name string `mapstructure:"name"`
+	// Type is either webhook, slack or telegram.
+	Type string `mapstructure:"type"`
+	// URL is the webhook URL, or the Telegram Bot API URL, which defaults
+	// to https://api.telegram.org.
+	URL string `mapstructure:"url"`
+	// Template is the Go template of the webhook body, the alert as JSON
+	// if not set.
+	Template string `mapstructure:"template"`
+	Token    string `mapstructure:"token"`
+	ChatID   string `mapstructure:"chat-id"`
+}
+
+var (
+	metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRegexp  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// reservedLabels are already used by the validator and wallet metrics, so
+// they cannot be set as target labels.
+var reservedLabels = []string{"address", "moniker", "denom", "chain_id"}
+
+// reservedPortfolioLabels are already used by the portfolio metrics.
+var reservedPortfolioLabels = []string{"portfolio", "address", "denom", "validator_address", "chain_id"}
+
+var (
+	OperationalWallets []OperationalWallet
+	ValidatorTargets   []Target
+	WalletTargets      []Target
+	Portfolios         []Portfolio
+	GenericQueries     []GenericQuery
+	PriceSources       []PriceSource
+	AlertRules         []AlertRule
+	AlertNotifiers     []AlertNotifierConfig
+)
+
+// loadConfigSections reads the config sections that cannot be passed as flags.
+func loadConfigSections() error {
+	if err := viper.UnmarshalKey("operational-wallets", &OperationalWallets); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("validators", &ValidatorTargets); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("wallets", &WalletTargets); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("portfolios", &Portfolios); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("queries", &GenericQueries); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("prices", &PriceSources); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("alerts", &AlertRules); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("notifiers", &AlertNotifiers); err != nil {
+		return err
+	}
+
+	for _, target := range append(ValidatorTargets, WalletTargets...) {
+		for _, label := range reservedLabels {
+			if _, ok := target.Labels[label]; ok {
+				return fmt.Errorf("target %s: label %q is reserved", target.Address, label)
+			}
+		}
+	}
+
+	portfolioNames := map[string]bool{}
+	for _, portfolio := range Portfolios {
+		if portfolio.Name == "" {
+			return fmt.Errorf("portfolio without a name")
+		}
+
+		if portfolioNames[portfolio.Name] {
+			return fmt.Errorf("portfolio %s is listed more than once", portfolio.Name)
+		}
+		portfolioNames[portfolio.Name] = true
+
+		for _, label := range reservedPortfolioLabels {
+			if _, ok := portfolio.Labels[label]; ok {
+				return fmt.Errorf("portfolio %s: label %q is reserved", portfolio.Name, label)
+			}
+		}
+	}
+
+	queryNames := map[string]bool{}
+	for _, query := range GenericQueries {
+		if err := validateGenericQuery(query); err != nil {
+			return fmt.Errorf("query %s: %w", query.Name, err)
+		}
+
+		if queryNames[query.Name] {
+			return fmt.Errorf("query %s is listed more than once", query.Name)
+		}
+		queryNames[query.Name] = true
+	}
+
+	prices := map[string]bool{}
+	for index := range PriceSources {
+		source := &PriceSources[index]
+		if source.Quote == "" {
+			source.Quote = defaultPriceQuote
+		}
+
+		if err := validatePriceSource(*source); err != nil {
+			return fmt.Errorf("price of %s: %w", source.Denom, err)
+		}
+
+		key := source.Denom + "/" + source.Quote
+		if prices[key] {
+			return fmt.Errorf("price of %s in %s is listed more than once", source.Denom, source.Quote)
+		}
+		prices[key] = true
+	}
+
+	notifierNames := map[string]bool{}
+	for _, notifier := range AlertNotifiers {
+		if err := validateAlertNotifier(notifier); err != nil {
+			return fmt.Errorf("notifier %s: %w", notifier.Name, err)
+		}
+
+		if notifierNames[notifier.Name] {
+			return fmt.Errorf("notifier %s is listed more than once", notifier.Name)
+		}
+		notifierNames[notifier.Name] = true
+	}
+
+	ruleNames := map[string]bool{}
+	for _, rule := range AlertRules {
+		if err := validateAlertRule(rule, notifierNames); err != nil {
+			return fmt.Errorf("alert %s: %w", rule.Name, err)
+		}
+
+		if ruleNames[rule.Name] {
+			return fmt.Errorf("alert %s is listed more than once", rule.Name)
+		}
+		ruleNames[rule.Name] = true
+	}
+
+	return nil
+}
+
+func validateGenericQuery(query GenericQuery) error {
+	if query.Name == "" || query.Service == "" || query.Method == "" {
+		return fmt.Errorf("name, service and method are required")
+	}
+
+	if len(query.Metrics) == 0 {
+		return fmt.Errorf("no metrics")
+	}
+
+	for _, metric := range query.Metrics {
+		if !metricNameRegexp.MatchString(metric.Name) {
+			return fmt.Errorf("invalid metric name %q", metric.Name)
+		}
+
+		if metric.Divisor < 0 {
+			return fmt.Errorf("metric %s: divisor cannot be negative", metric.Name)
+		}
+
+		for label := range metric.Labels {
+			if !labelNameRegexp.MatchString(label) || label == "chain_id" {
+				return fmt.Errorf("metric %s: invalid label name %q", metric.Name, label)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validatePriceSource(source PriceSource) error {
+	if source.Denom == "" {
+		return fmt.Errorf("denom is required")
+	}
+
+	switch source.Provider {
+	case "static":
+		if source.Price <= 0 {
+			return fmt.Errorf("price must be positive")
+		}
+	case "http":
+		if source.URL == "" || source.Field == "" {
+			return fmt.Errorf("url and field are required")
+		}
+	case "file":
+		if source.File == "" {
+			return fmt.Errorf("file is required")
+		}
+	default:
+		return fmt.Errorf("unsupported provider %q, expected static, http or file", source.Provider)
+	}
+
+	return nil
+}
+
+func validateAlertRule(rule AlertRule, notifierNames map[string]bool) error {
+	if rule.Name == "" || rule.Address == "" {
+		return fmt.Errorf("name and address are required")
+	}
+
+	switch rule.Type {
+	case alertTypeJailed, alertTypeMissedBlocks, alertTypeInactive, alertTypeLowBalance, alertTypeGovVoteMissing:
+	default:
+		return fmt.Errorf("unsupported type %q", rule.Type)
+	}
+
+	if rule.Threshold < 0 {
+		return fmt.Errorf("threshold cannot be negative")
+	}
+
+	for _, notifier := range rule.Notifiers {
+		if !notifierNames[notifier] {
+			return fmt.Errorf("unknown notifier %s", notifier)
+		}
+	}
+
+	return nil
+}
+
+func validateAlertNotifier(notifier AlertNotifierConfig) error {
+	if notifier.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch notifier.Type {
+	case "webhook":
+		if notifier.URL == "" {
+			return fmt.Errorf("url is required")
+		}
+
+		if notifier.Template != "" {
+			if _, err := template.New(notifier.Name).Parse(notifier.Template); err != nil {
+				return fmt.Errorf("invalid template: %w", err)
+			}
+		}
+	case "slack":
+		if notifier.URL == "" {
+			return fmt.Errorf("url is required")
+		}
+	case "telegram":
+		if notifier.Token == "" || notifier.ChatID == "" {
+			return fmt.Errorf("token and chat-id are required")
+		}
+	default:
+		return fmt.Errorf("unsupported type %q, expected webhook, slack or telegram", notifier.Type)
+	}
+
+	return nil
+}
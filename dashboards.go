@@ -0,0 +1,656 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+const (
+	dashboardsDir = "dashboards"
+
+	unitAmount    = "locale"
+	unitRatio     = "percentunit"
+	unitSeconds   = "s"
+	unitTimestamp = "dateTimeFromNow"
+	unitNone      = "none"
+
+	panelStat       = "stat"
+	panelTimeseries = "timeseries"
+	panelTable      = "table"
+
+	grafanaVersion = "8.1.0"
+)
+
+// panelNames are the names of the panel plugins in the dashboard requires.
+var panelNames = map[string]string{
+	panelStat:       "Stat",
+	panelTimeseries: "Time series",
+	panelTable:      "Table",
+}
+
+var DashboardsOutput string
+
+// dashboardFiles are the dashboards generated by the dashboards subcommand,
+// served on /dashboards/.
+//
+//go:embed dashboards/*.json
+var dashboardFiles embed.FS
+
+// Dashboard is a Grafana dashboard made of the panels of the metric catalog
+// with the same dashboard name.
+type Dashboard struct {
+	Name        string
+	UID         string
+	Title       string
+	Description string
+	Tags        []string
+	Variables   []DashboardVariable
+	// Selector is the label matchers every metric of the dashboard is
+	// filtered by.
+	Selector string
+	// Aggregation is how the series of a panel are aggregated, max for the
+	// chain-wide metrics that several exporters can export the same way.
+	Aggregation string
+}
+
+type DashboardVariable struct {
+	Name   string
+	Label  string
+	Query  string
+	Hidden bool
+}
+
+// DashboardMetric is a panel of a dashboard showing an exported metric.
+type DashboardMetric struct {
+	Dashboard string
+	Row       string
+	Metric    string
+	Title     string
+	Panel     string
+	Unit      string
+	// By are the labels the series are aggregated by and named after.
+	By []string
+	// Expr replaces the aggregation of the dashboard, with %s being the
+	// metric with the dashboard selector.
+	Expr string
+}
+
+var dashboards = []Dashboard{
+	{
+		Name:        "cosmos-validator",
+		UID:         "dtfjRVM7z",
+		Title:       "Cosmos Validator",
+		Description: "Stats of a Cosmos validator",
+		Tags:        []string{"cosmos", "blockchain", "validator"},
+		Variables: []DashboardVariable{
+			{Name: "chain_id", Label: "Chain ID", Query: "label_values(cosmos_validator_tokens, chain_id)"},
+			{Name: "moniker", Label: "Moniker", Query: `label_values(cosmos_validator_tokens{chain_id="$chain_id"}, moniker)`},
+			// The accrual metrics have no moniker label.
+			{Name: "address", Label: "Address", Query: `label_values(cosmos_validator_tokens{chain_id="$chain_id", moniker="$moniker"}, address)`, Hidden: true},
+		},
+		Selector:    `chain_id="$chain_id", address="$address"`,
+		Aggregation: "sum",
+	},
+	{
+		Name:        "cosmos-validators-set",
+		UID:         "7gwle7Gnz",
+		Title:       "Cosmos Validators",
+		Description: "Stats of a Cosmos validators set",
+		Tags:        []string{"cosmos", "blockchain"},
+		Variables: []DashboardVariable{
+			{Name: "chain_id", Label: "Chain ID", Query: "label_values(cosmos_validators_rank, chain_id)"},
+		},
+		Selector:    `chain_id="$chain_id"`,
+		Aggregation: "max",
+	},
+	{
+		Name:        "cosmos-wallet",
+		UID:         "gpQrRVGnz",
+		Title:       "Cosmos Wallet",
+		Description: "Stats of a Cosmos wallet",
+		Tags:        []string{"cosmos", "blockchain", "wallet"},
+		Variables: []DashboardVariable{
+			{Name: "chain_id", Label: "Chain ID", Query: "label_values(cosmos_wallet_balance, chain_id)"},
+			{Name: "address", Label: "Address", Query: `label_values(cosmos_wallet_balance{chain_id="$chain_id"}, address)`},
+		},
+		Selector:    `chain_id="$chain_id", address="$address"`,
+		Aggregation: "sum",
+	},
+	{
+		Name:        "cosmos-general",
+		UID:         "cosmos-general",
+		Title:       "Cosmos General",
+		Description: "Supply, staking and economics of a Cosmos chain",
+		Tags:        []string{"cosmos", "blockchain"},
+		Variables: []DashboardVariable{
+			{Name: "chain_id", Label: "Chain ID", Query: "label_values(cosmos_general_bonded_tokens, chain_id)"},
+		},
+		Selector:    `chain_id="$chain_id"`,
+		Aggregation: "max",
+	},
+	{
+		Name:        "cosmos-params",
+		UID:         "cosmos-params",
+		Title:       "Cosmos Params",
+		Description: "Staking, slashing, mint and distribution params of a Cosmos chain",
+		Tags:        []string{"cosmos", "blockchain"},
+		Variables: []DashboardVariable{
+			{Name: "chain_id", Label: "Chain ID", Query: "label_values(cosmos_params_max_validators, chain_id)"},
+		},
+		Selector:    `chain_id="$chain_id"`,
+		Aggregation: "max",
+	},
+	{
+		Name:        "cosmos-node",
+		UID:         "cosmos-node",
+		Title:       "Cosmos Exporter Node",
+		Description: "Node capabilities, queries, prices and alerts of the exporter",
+		Tags:        []string{"cosmos", "blockchain", "exporter"},
+		Variables: []DashboardVariable{
+			{Name: "chain_id", Label: "Chain ID", Query: "label_values(cosmos_exporter_snapshot_height, chain_id)"},
+		},
+		Selector:    `chain_id="$chain_id"`,
+		Aggregation: "max",
+	},
+}
+
+// dashboardMetrics is the catalog of the metrics shown on the dashboards, in
+// the order of the panels.
+var dashboardMetrics = []DashboardMetric{
+	{Dashboard: "cosmos-validator", Row: "Status", Metric: "cosmos_validator_rank", Title: "rank", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-validator", Row: "Status", Metric: "cosmos_validator_bonded_rank", Title: "bonded rank", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-validator", Row: "Status", Metric: "cosmos_validator_active", Title: "active", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-validator", Row: "Status", Metric: "cosmos_validator_in_consensus_set", Title: "in consensus set", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-validator", Row: "Status", Metric: "cosmos_validator_active_mismatch", Title: "active mismatch", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-validator", Row: "Status", Metric: "cosmos_validator_jailed", Title: "jailed", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-validator", Row: "Status", Metric: "cosmos_validator_status", Title: "status", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-validator", Row: "Status", Metric: "cosmos_validator_commission_rate", Title: "commission rate", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-validator", Row: "Status", Metric: "cosmos_validator_delegator_apr", Title: "delegator APR", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-validator", Row: "Status", Metric: "cosmos_validator_missed_blocks", Title: "missed blocks", Panel: panelTimeseries, Unit: unitNone},
+	{Dashboard: "cosmos-validator", Row: "Stake", Metric: "cosmos_validator_tokens", Title: "tokens", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-validator", Row: "Stake", Metric: "cosmos_validator_delegations", Title: "delegations", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-validator", Row: "Stake", Metric: "cosmos_validator_delegators_shares", Title: "delegators shares", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-validator", Row: "Stake", Metric: "cosmos_validator_delegations", Title: "biggest delegators", Panel: panelTable, Unit: unitAmount, Expr: "topk(20, sum by (delegated_by, denom) (%s))"},
+	{Dashboard: "cosmos-validator", Row: "Stake", Metric: "cosmos_validator_unbondings", Title: "unbondings", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-validator", Row: "Stake", Metric: "cosmos_validator_redelegations", Title: "redelegations", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-validator", Row: "Stake", Metric: "cosmos_validator_unbondings_maturing", Title: "unbondings maturing", Panel: panelTable, Unit: unitAmount, By: []string{"denom", "within"}},
+	{Dashboard: "cosmos-validator", Row: "Stake", Metric: "cosmos_validator_redelegations_maturing", Title: "redelegations maturing", Panel: panelTable, Unit: unitAmount, By: []string{"denom", "within"}},
+	{Dashboard: "cosmos-validator", Row: "Stake", Metric: "cosmos_validator_unbondings_next_completion_time", Title: "next unbonding completion", Panel: panelStat, Unit: unitTimestamp, Expr: "min(%s)"},
+	{Dashboard: "cosmos-validator", Row: "Stake", Metric: "cosmos_validator_redelegations_next_completion_time", Title: "next redelegation completion", Panel: panelStat, Unit: unitTimestamp, Expr: "min(%s)"},
+	{Dashboard: "cosmos-validator", Row: "Commission and rewards", Metric: "cosmos_validator_commission", Title: "commission", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-validator", Row: "Commission and rewards", Metric: "cosmos_validator_rewards", Title: "rewards", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-validator", Row: "Commission and rewards", Metric: "cosmos_validator_delegators_rewards", Title: "delegators rewards", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-validator", Row: "Commission and rewards", Metric: "cosmos_validator_commission_earned_total", Title: "commission earned per day", Panel: panelTimeseries, Unit: unitAmount, Expr: "sum by (denom) (increase(%s[1d]))"},
+	{Dashboard: "cosmos-validator", Row: "Commission and rewards", Metric: "cosmos_validator_commission_withdrawals_total", Title: "commission withdrawals per day", Panel: panelTimeseries, Unit: unitNone, Expr: "sum(increase(%s[1d]))"},
+
+	{Dashboard: "cosmos-validators-set", Row: "Overview", Metric: "cosmos_validators_active", Title: "total validators", Panel: panelStat, Unit: unitNone, Expr: "count(%s)"},
+	{Dashboard: "cosmos-validators-set", Row: "Overview", Metric: "cosmos_validators_active", Title: "active validators", Panel: panelStat, Unit: unitNone, Expr: "count(%s == 1)"},
+	{Dashboard: "cosmos-validators-set", Row: "Overview", Metric: "cosmos_validators_active", Title: "inactive validators", Panel: panelStat, Unit: unitNone, Expr: "count(%s == 0)"},
+	{Dashboard: "cosmos-validators-set", Row: "Overview", Metric: "cosmos_validators_jailed", Title: "jailed validators", Panel: panelStat, Unit: unitNone, Expr: "count(%s == 1)"},
+	{Dashboard: "cosmos-validators-set", Row: "Overview", Metric: "cosmos_validators_in_consensus_set", Title: "in consensus set", Panel: panelStat, Unit: unitNone, Expr: "count(%s == 1)"},
+	{Dashboard: "cosmos-validators-set", Row: "Overview", Metric: "cosmos_validators_active_mismatch", Title: "active mismatches", Panel: panelStat, Unit: unitNone, Expr: "count(%s == 1)"},
+	{Dashboard: "cosmos-validators-set", Row: "Validators", Metric: "cosmos_validators_tokens", Title: "tokens", Panel: panelTable, Unit: unitAmount, Expr: "sort_desc(max by (moniker, address, denom) (%s))"},
+	{Dashboard: "cosmos-validators-set", Row: "Validators", Metric: "cosmos_validators_bonded_rank", Title: "bonded rank", Panel: panelTable, Unit: unitNone, Expr: "sort(max by (moniker, address) (%s))"},
+	{Dashboard: "cosmos-validators-set", Row: "Validators", Metric: "cosmos_validators_commission", Title: "commission", Panel: panelTable, Unit: unitRatio, By: []string{"moniker", "address"}},
+	{Dashboard: "cosmos-validators-set", Row: "Validators", Metric: "cosmos_validators_delegator_apr", Title: "delegator APR", Panel: panelTable, Unit: unitRatio, By: []string{"moniker", "address"}},
+	{Dashboard: "cosmos-validators-set", Row: "Validators", Metric: "cosmos_validators_delegator_shares", Title: "delegator shares", Panel: panelTimeseries, Unit: unitAmount, Expr: "topk(20, max by (moniker) (%s))"},
+	{Dashboard: "cosmos-validators-set", Row: "Validators", Metric: "cosmos_validators_min_self_delegation", Title: "min self delegation", Panel: panelTable, Unit: unitAmount, By: []string{"moniker", "address"}},
+	{Dashboard: "cosmos-validators-set", Row: "Validators", Metric: "cosmos_validators_status", Title: "status", Panel: panelTable, Unit: unitNone, By: []string{"moniker", "address"}},
+	{Dashboard: "cosmos-validators-set", Row: "Validators", Metric: "cosmos_validators_jailed", Title: "jailed", Panel: panelTable, Unit: unitNone, Expr: "max by (moniker, address) (%s) == 1"},
+	{Dashboard: "cosmos-validators-set", Row: "Validators", Metric: "cosmos_validators_missed_blocks", Title: "missed blocks", Panel: panelTimeseries, Unit: unitNone, Expr: "topk(20, max by (moniker) (%s) > 0)"},
+
+	{Dashboard: "cosmos-wallet", Row: "Balances", Metric: "cosmos_wallet_balance", Title: "balance", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Balances", Metric: "cosmos_wallet_spendable_balance", Title: "spendable balance", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Balances", Metric: "cosmos_wallet_sequence", Title: "sequence", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-wallet", Row: "Balances", Metric: "cosmos_wallet_account_number", Title: "account number", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-wallet", Row: "Balances", Metric: "cosmos_wallet_pubkey_set", Title: "pubkey set", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-wallet", Row: "Balances", Metric: "cosmos_wallet_account_info", Title: "account type", Panel: panelTable, Unit: unitNone, By: []string{"type"}},
+	{Dashboard: "cosmos-wallet", Row: "Staking", Metric: "cosmos_wallet_delegations", Title: "delegations", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Staking", Metric: "cosmos_wallet_delegations", Title: "delegations by validator", Panel: panelTable, Unit: unitAmount, By: []string{"delegated_to", "denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Staking", Metric: "cosmos_wallet_redelegations", Title: "redelegations", Panel: panelTable, Unit: unitAmount, By: []string{"redelegated_from", "redelegated_to", "denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Staking", Metric: "cosmos_wallet_unbondings", Title: "unbondings", Panel: panelTable, Unit: unitAmount, By: []string{"unbonded_from", "denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Staking", Metric: "cosmos_wallet_unbondings_maturing", Title: "unbondings maturing", Panel: panelTable, Unit: unitAmount, By: []string{"denom", "within"}},
+	{Dashboard: "cosmos-wallet", Row: "Staking", Metric: "cosmos_wallet_redelegations_maturing", Title: "redelegations maturing", Panel: panelTable, Unit: unitAmount, By: []string{"denom", "within"}},
+	{Dashboard: "cosmos-wallet", Row: "Staking", Metric: "cosmos_wallet_unbondings_next_completion_time", Title: "next unbonding completion", Panel: panelStat, Unit: unitTimestamp, Expr: "min(%s)"},
+	{Dashboard: "cosmos-wallet", Row: "Staking", Metric: "cosmos_wallet_redelegations_next_completion_time", Title: "next redelegation completion", Panel: panelStat, Unit: unitTimestamp, Expr: "min(%s)"},
+	{Dashboard: "cosmos-wallet", Row: "Rewards", Metric: "cosmos_wallet_rewards_sum", Title: "rewards", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Rewards", Metric: "cosmos_wallet_rewards", Title: "rewards by validator", Panel: panelTable, Unit: unitAmount, By: []string{"validator_address", "denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Rewards", Metric: "cosmos_wallet_rewards_earned_total", Title: "rewards earned per day", Panel: panelTimeseries, Unit: unitAmount, Expr: "sum by (denom) (increase(%s[1d]))"},
+	{Dashboard: "cosmos-wallet", Row: "Rewards", Metric: "cosmos_wallet_rewards_withdrawals_total", Title: "rewards withdrawals per day", Panel: panelTimeseries, Unit: unitNone, Expr: "sum(increase(%s[1d]))"},
+	{Dashboard: "cosmos-wallet", Row: "Vesting", Metric: "cosmos_wallet_vesting_original", Title: "original vesting", Panel: panelStat, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Vesting", Metric: "cosmos_wallet_vesting_locked", Title: "locked", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Vesting", Metric: "cosmos_wallet_vesting_unlocked", Title: "unlocked", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Vesting", Metric: "cosmos_wallet_vesting_delegated_vesting", Title: "delegated vesting", Panel: panelStat, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Vesting", Metric: "cosmos_wallet_vesting_delegated_free", Title: "delegated free", Panel: panelStat, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Vesting", Metric: "cosmos_wallet_vesting_next_unlock_amount", Title: "next unlock amount", Panel: panelStat, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-wallet", Row: "Vesting", Metric: "cosmos_wallet_vesting_next_unlock_time", Title: "next unlock", Panel: panelStat, Unit: unitTimestamp, Expr: "min(%s)"},
+	{Dashboard: "cosmos-wallet", Row: "Vesting", Metric: "cosmos_wallet_vesting_start_time", Title: "vesting start", Panel: panelStat, Unit: unitTimestamp, Expr: "min(%s)"},
+	{Dashboard: "cosmos-wallet", Row: "Vesting", Metric: "cosmos_wallet_vesting_end_time", Title: "vesting end", Panel: panelStat, Unit: unitTimestamp, Expr: "max(%s)"},
+
+	{Dashboard: "cosmos-general", Row: "Staking", Metric: "cosmos_general_bonded_ratio", Title: "bonded ratio", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-general", Row: "Staking", Metric: "cosmos_general_nominal_apr", Title: "nominal APR", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-general", Row: "Staking", Metric: "cosmos_general_real_apr", Title: "real APR", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-general", Row: "Staking", Metric: "cosmos_general_inflation", Title: "inflation", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-general", Row: "Staking", Metric: "cosmos_general_bonded_tokens", Title: "bonded tokens", Panel: panelTimeseries, Unit: unitAmount},
+	{Dashboard: "cosmos-general", Row: "Staking", Metric: "cosmos_general_not_bonded_tokens", Title: "not bonded tokens", Panel: panelTimeseries, Unit: unitAmount},
+	{Dashboard: "cosmos-general", Row: "Staking", Metric: "cosmos_general_unbonding_tokens", Title: "unbonding tokens by days left", Panel: panelTimeseries, Unit: unitAmount, By: []string{"days"}},
+	{Dashboard: "cosmos-general", Row: "Staking", Metric: "cosmos_general_validator_stake_flow", Title: "validators stake flow", Panel: panelTable, Unit: unitAmount, Expr: "sort(max by (moniker, address) (%s))"},
+	{Dashboard: "cosmos-general", Row: "Staking", Metric: "cosmos_general_unbonding_queue_refreshed_at", Title: "unbonding queue refreshed", Panel: panelStat, Unit: unitTimestamp},
+	{Dashboard: "cosmos-general", Row: "Supply", Metric: "cosmos_general_supply_total", Title: "total supply", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-general", Row: "Supply", Metric: "cosmos_general_circulating_supply", Title: "circulating supply", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-general", Row: "Supply", Metric: "cosmos_general_circulating_supply_excluded", Title: "excluded from the circulating supply", Panel: panelTable, Unit: unitAmount, By: []string{"denom", "component"}},
+	{Dashboard: "cosmos-general", Row: "Supply", Metric: "cosmos_general_circulating_supply_skipped_accounts", Title: "accounts left out of the circulating supply", Panel: panelTable, Unit: unitNone, By: []string{"type"}},
+	{Dashboard: "cosmos-general", Row: "Supply", Metric: "cosmos_general_community_pool", Title: "community pool", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denom"}},
+	{Dashboard: "cosmos-general", Row: "Supply", Metric: "cosmos_general_annual_provisions", Title: "annual provisions", Panel: panelTimeseries, Unit: unitAmount, By: []string{"denoms"}},
+
+	{Dashboard: "cosmos-params", Row: "Staking", Metric: "cosmos_params_max_validators", Title: "active set length", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-params", Row: "Staking", Metric: "cosmos_params_unbonding_time", Title: "unbonding time", Panel: panelStat, Unit: unitSeconds},
+	{Dashboard: "cosmos-params", Row: "Mint", Metric: "cosmos_params_blocks_per_year", Title: "blocks per year", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-params", Row: "Mint", Metric: "cosmos_params_goal_bonded", Title: "goal bonded", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-params", Row: "Mint", Metric: "cosmos_params_inflation_min", Title: "min inflation", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-params", Row: "Mint", Metric: "cosmos_params_inflation_max", Title: "max inflation", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-params", Row: "Mint", Metric: "cosmos_params_inflation_rate_change", Title: "inflation rate change", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-params", Row: "Slashing", Metric: "cosmos_params_signed_blocks_window", Title: "signed blocks window", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-params", Row: "Slashing", Metric: "cosmos_params_min_signed_per_window", Title: "min signed per window", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-params", Row: "Slashing", Metric: "cosmos_params_downtime_jail_duration", Title: "downtime jail duration", Panel: panelStat, Unit: unitSeconds},
+	{Dashboard: "cosmos-params", Row: "Slashing", Metric: "cosmos_params_slash_fraction_downtime", Title: "downtime slash fraction", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-params", Row: "Slashing", Metric: "cosmos_params_slash_fraction_double_sign", Title: "double sign slash fraction", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-params", Row: "Distribution", Metric: "cosmos_params_community_tax", Title: "community tax", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-params", Row: "Distribution", Metric: "cosmos_params_base_proposer_reward", Title: "base proposer reward", Panel: panelStat, Unit: unitRatio},
+	{Dashboard: "cosmos-params", Row: "Distribution", Metric: "cosmos_params_bonus_proposer_reward", Title: "bonus proposer reward", Panel: panelStat, Unit: unitRatio},
+
+	{Dashboard: "cosmos-node", Row: "Node", Metric: "cosmos_exporter_snapshot_height", Title: "snapshot height", Panel: panelTimeseries, Unit: unitNone, By: []string{"source"}},
+	{Dashboard: "cosmos-node", Row: "Node", Metric: "cosmos_exporter_capabilities_probed", Title: "capabilities probed", Panel: panelStat, Unit: unitNone},
+	{Dashboard: "cosmos-node", Row: "Node", Metric: "cosmos_exporter_module_available", Title: "modules", Panel: panelTable, Unit: unitNone, By: []string{"module", "service"}},
+	{Dashboard: "cosmos-node", Row: "Node", Metric: "cosmos_exporter_node_service", Title: "services", Panel: panelTable, Unit: unitNone, By: []string{"service"}},
+	{Dashboard: "cosmos-node", Row: "Node", Metric: "cosmos_exporter_query_success", Title: "generic queries success", Panel: panelTimeseries, Unit: unitNone, By: []string{"query"}},
+	{Dashboard: "cosmos-node", Row: "Prices", Metric: "cosmos_price", Title: "prices", Panel: panelTimeseries, Unit: unitNone, By: []string{"denom", "quote"}},
+	{Dashboard: "cosmos-node", Row: "Prices", Metric: "cosmos_price_stale", Title: "stale prices", Panel: panelStat, Unit: unitNone, Expr: "count(%s == 1) or vector(0)"},
+	{Dashboard: "cosmos-node", Row: "Prices", Metric: "cosmos_price_refresh_success", Title: "price refreshes", Panel: panelTable, Unit: unitNone, By: []string{"denom", "quote"}},
+	{Dashboard: "cosmos-node", Row: "Alerts", Metric: "cosmos_alert_firing", Title: "firing alerts", Panel: panelTable, Unit: unitNone, Expr: "max by (alert, type, address) (%s) == 1"},
+	{Dashboard: "cosmos-node", Row: "Alerts", Metric: "cosmos_alert_evaluation_success", Title: "alert evaluations", Panel: panelTable, Unit: unitNone, By: []string{"alert"}},
+}
+
+// getDashboardExpr returns the query of the panel. The timestamps are
+// exported in seconds, while Grafana expects them in milliseconds.
+func getDashboardExpr(dashboard Dashboard, metric DashboardMetric) string {
+	selected := fmt.Sprintf("%s{%s}", metric.Metric, dashboard.Selector)
+
+	var expr string
+	switch {
+	case metric.Expr != "":
+		expr = fmt.Sprintf(metric.Expr, selected)
+	case len(metric.By) > 0:
+		expr = fmt.Sprintf("%s by (%s) (%s)", dashboard.Aggregation, strings.Join(metric.By, ", "), selected)
+	default:
+		expr = fmt.Sprintf("%s(%s)", dashboard.Aggregation, selected)
+	}
+
+	if metric.Unit == unitTimestamp {
+		expr += " * 1000"
+	}
+
+	return expr
+}
+
+// getDashboardLegend names the series after the labels they're aggregated
+// by, or after the panel if they aren't.
+func getDashboardLegend(metric DashboardMetric) string {
+	if len(metric.By) == 0 {
+		return metric.Title
+	}
+
+	parts := make([]string, len(metric.By))
+	for index, label := range metric.By {
+		parts[index] = "{{ " + label + " }}"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func getDashboardPanelSize(panel string) (int, int) {
+	switch panel {
+	case panelStat:
+		return 4, 4
+	default:
+		return 12, 8
+	}
+}
+
+// generateDashboard returns the Grafana dashboard model, with the panels of
+// the metric catalog laid out left to right under a row per group.
+func generateDashboard(dashboard Dashboard) map[string]interface{} {
+	var (
+		panels       []interface{}
+		id           int
+		x, y, height int
+		row          string
+	)
+
+	panelTypes := map[string]bool{}
+
+	for _, metric := range dashboardMetrics {
+		if metric.Dashboard != dashboard.Name {
+			continue
+		}
+
+		if metric.Row != row || len(panels) == 0 {
+			if len(panels) > 0 {
+				y += height
+			}
+
+			id++
+			panels = append(panels, map[string]interface{}{
+				"collapsed":  false,
+				"datasource": nil,
+				"gridPos":    map[string]interface{}{"h": 1, "w": 24, "x": 0, "y": y},
+				"id":         id,
+				"panels":     []interface{}{},
+				"title":      metric.Row,
+				"type":       "row",
+			})
+
+			row = metric.Row
+			x, height = 0, 0
+			y++
+		}
+
+		width, panelHeight := getDashboardPanelSize(metric.Panel)
+		if x+width > 24 {
+			x = 0
+			y += height
+			height = 0
+		}
+
+		id++
+		panels = append(panels, generateDashboardPanel(dashboard, metric, id, map[string]interface{}{
+			"h": panelHeight,
+			"w": width,
+			"x": x,
+			"y": y,
+		}))
+
+		panelTypes[metric.Panel] = true
+
+		x += width
+		if panelHeight > height {
+			height = panelHeight
+		}
+	}
+
+	requires := []interface{}{
+		map[string]interface{}{"type": "grafana", "id": "grafana", "name": "Grafana", "version": grafanaVersion},
+		map[string]interface{}{"type": "datasource", "id": "prometheus", "name": "Prometheus", "version": "1.0.0"},
+	}
+
+	types := make([]string, 0, len(panelTypes))
+	for panelType := range panelTypes {
+		types = append(types, panelType)
+	}
+
+	sort.Strings(types)
+
+	for _, panelType := range types {
+		requires = append(requires, map[string]interface{}{
+			"type":    "panel",
+			"id":      panelType,
+			"name":    panelNames[panelType],
+			"version": "",
+		})
+	}
+
+	variables := make([]interface{}, len(dashboard.Variables))
+	for index, variable := range dashboard.Variables {
+		hide := 0
+		if variable.Hidden {
+			hide = 2
+		}
+
+		sortOrder := 0
+		if variable.Name == "chain_id" {
+			sortOrder = 1
+		}
+
+		variables[index] = map[string]interface{}{
+			"current":    map[string]interface{}{},
+			"datasource": "${DS_PROMETHEUS}",
+			"definition": variable.Query,
+			"hide":       hide,
+			"includeAll": false,
+			"label":      variable.Label,
+			"multi":      false,
+			"name":       variable.Name,
+			"options":    []interface{}{},
+			"query": map[string]interface{}{
+				"query": variable.Query,
+				"refId": "StandardVariableQuery",
+			},
+			"refresh":     1,
+			"regex":       "",
+			"skipUrlSync": false,
+			"sort":        sortOrder,
+			"type":        "query",
+		}
+	}
+
+	return map[string]interface{}{
+		"__inputs": []interface{}{
+			map[string]interface{}{
+				"name":        "DS_PROMETHEUS",
+				"label":       "Prometheus",
+				"description": "",
+				"type":        "datasource",
+				"pluginId":    "prometheus",
+				"pluginName":  "Prometheus",
+			},
+		},
+		"__requires": requires,
+		"annotations": map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{
+					"builtIn":    1,
+					"datasource": "-- Grafana --",
+					"enable":     true,
+					"hide":       true,
+					"iconColor":  "rgba(0, 211, 255, 1)",
+					"name":       "Annotations & Alerts",
+					"type":       "dashboard",
+				},
+			},
+		},
+		"description":   dashboard.Description,
+		"editable":      true,
+		"graphTooltip":  0,
+		"id":            nil,
+		"links":         []interface{}{},
+		"panels":        panels,
+		"refresh":       false,
+		"schemaVersion": 30,
+		"style":         "dark",
+		"tags":          dashboard.Tags,
+		"templating":    map[string]interface{}{"list": variables},
+		"time":          map[string]interface{}{"from": "now-30m", "to": "now"},
+		"timepicker":    map[string]interface{}{},
+		"timezone":      "",
+		"title":         dashboard.Title,
+		"uid":           dashboard.UID,
+		"version":       1,
+	}
+}
+
+func generateDashboardPanel(dashboard Dashboard, metric DashboardMetric, id int, gridPos map[string]interface{}) map[string]interface{} {
+	target := map[string]interface{}{
+		"exemplar":     false,
+		"expr":         getDashboardExpr(dashboard, metric),
+		"interval":     "",
+		"legendFormat": getDashboardLegend(metric),
+		"refId":        "A",
+	}
+
+	color := map[string]interface{}{"mode": "palette-classic"}
+	if metric.Panel == panelStat {
+		color = map[string]interface{}{"mode": "fixed", "fixedColor": "text"}
+	}
+
+	panel := map[string]interface{}{
+		"datasource": nil,
+		"fieldConfig": map[string]interface{}{
+			"defaults": map[string]interface{}{
+				"color":    color,
+				"mappings": []interface{}{},
+				"unit":     metric.Unit,
+			},
+			"overrides": []interface{}{},
+		},
+		"gridPos":       gridPos,
+		"id":            id,
+		"pluginVersion": grafanaVersion,
+		"targets":       []interface{}{target},
+		"title":         metric.Title,
+		"type":          metric.Panel,
+	}
+
+	switch metric.Panel {
+	case panelStat:
+		panel["options"] = map[string]interface{}{
+			"colorMode":   "value",
+			"graphMode":   "none",
+			"justifyMode": "auto",
+			"orientation": "auto",
+			"reduceOptions": map[string]interface{}{
+				"calcs":  []string{"lastNotNull"},
+				"fields": "",
+				"values": false,
+			},
+			"text":     map[string]interface{}{},
+			"textMode": "auto",
+		}
+	case panelTable:
+		target["instant"] = true
+		target["format"] = "table"
+		panel["options"] = map[string]interface{}{"showHeader": true}
+		panel["transformations"] = []interface{}{
+			map[string]interface{}{
+				"id": "organize",
+				"options": map[string]interface{}{
+					"excludeByName": map[string]interface{}{"Time": true},
+					"renameByName":  map[string]interface{}{"Value": metric.Title},
+				},
+			},
+		}
+	default:
+		panel["options"] = map[string]interface{}{
+			"legend":  map[string]interface{}{"calcs": []string{}, "displayMode": "list", "placement": "bottom"},
+			"tooltip": map[string]interface{}{"mode": "multi"},
+		}
+	}
+
+	return panel
+}
+
+// writeDashboard writes the dashboard the way Grafana exports them, with the
+// keys sorted and the PromQL operators not escaped.
+func writeDashboard(buffer *bytes.Buffer, dashboard Dashboard) error {
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(generateDashboard(dashboard))
+}
+
+// DashboardsHandler serves the dashboards embedded at build time, or their
+// list on /dashboards/.
+func DashboardsHandler(w http.ResponseWriter, r *http.Request) {
+	requestStart := time.Now()
+
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	name := strings.TrimPrefix(r.URL.Path, "/dashboards/")
+
+	if name == "" {
+		files, err := fs.Glob(dashboardFiles, path.Join(dashboardsDir, "*.json"))
+		if err != nil {
+			sublogger.Error().Err(err).Msg("Could not list dashboards")
+			http.Error(w, "Could not list dashboards", http.StatusInternalServerError)
+			return
+		}
+
+		names := make([]string, len(files))
+		for index, file := range files {
+			names[index] = path.Base(file)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(names); err != nil {
+			sublogger.Error().Err(err).Msg("Could not write dashboards list")
+		}
+	} else {
+		if path.Base(name) != name || path.Ext(name) != ".json" {
+			http.NotFound(w, r)
+			return
+		}
+
+		content, err := dashboardFiles.ReadFile(path.Join(dashboardsDir, name))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(content); err != nil {
+			sublogger.Error().Err(err).Msg("Could not write dashboard")
+		}
+	}
+
+	sublogger.Info().
+		Str("method", "GET").
+		Str("endpoint", "/dashboards/").
+		Str("dashboard", name).
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
+
+var dashboardsCmd = &cobra.Command{
+	Use:   "dashboards",
+	Short: "Generate the Grafana dashboards for the exported metrics",
+	Long: "Write the Grafana dashboards for the validator, validators set, wallet, general, params " +
+		"and node metrics, generated from the metric catalog and templated on the chain_id. " +
+		"The ones in the dashboards directory are embedded into the binary at build time and served on /dashboards/.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		setLogger()
+
+		if err := os.MkdirAll(DashboardsOutput, 0o755); err != nil {
+			log.Fatal().Err(err).Msg("Could not create the output directory")
+		}
+
+		for _, dashboard := range dashboards {
+			var buffer bytes.Buffer
+			if err := writeDashboard(&buffer, dashboard); err != nil {
+				log.Fatal().Err(err).Str("dashboard", dashboard.Name).Msg("Could not generate dashboard")
+			}
+
+			file := filepath.Join(DashboardsOutput, dashboard.Name+".json")
+			if err := os.WriteFile(file, buffer.Bytes(), 0o644); err != nil {
+				log.Fatal().Err(err).Str("file", file).Msg("Could not write dashboard")
+			}
+
+			log.Info().Str("file", file).Msg("Wrote dashboard")
+		}
+	},
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"path"
+	"testing"
+)
+
+// TestDashboardsUpToDate checks that the embedded dashboards are the ones
+// generated from the metric catalog.
+func TestDashboardsUpToDate(t *testing.T) {
+	for _, dashboard := range dashboards {
+		t.Run(dashboard.Name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := writeDashboard(&buffer, dashboard); err != nil {
+				t.Fatalf("could not generate dashboard: %s", err)
+			}
+
+			file := path.Join(dashboardsDir, dashboard.Name+".json")
+			embedded, err := dashboardFiles.ReadFile(file)
+			if err != nil {
+				t.Fatalf("could not read %s: %s", file, err)
+			}
+
+			if !bytes.Equal(buffer.Bytes(), embedded) {
+				t.Errorf("%s differs from the metric catalog, run go run . dashboards if expected", file)
+			}
+		})
+	}
+}
+
+// TestDashboardMetrics checks that all the metrics of the catalog are still
+// exported under the same names, and are on a dashboard that exists.
+func TestDashboardMetrics(t *testing.T) {
+	sources := getExporterSources(t, "dashboards.go")
+
+	names := map[string]bool{}
+	for _, dashboard := range dashboards {
+		names[dashboard.Name] = true
+	}
+
+	for _, metric := range dashboardMetrics {
+		if !isMetricExported(sources, metric.Metric) {
+			t.Errorf("metric %s on the %s dashboard is not exported", metric.Metric, metric.Dashboard)
+		}
+
+		if !names[metric.Dashboard] {
+			t.Errorf("metric %s is on the unknown %s dashboard", metric.Metric, metric.Dashboard)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	minttypes "github.com/cosmos/cosmos-sdk/x/mint/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// Inputs used by the derived staking metrics, exported as the "inputs" label
+// so it's clear what each of them was computed from.
+const (
+	bondedRatioInputs  = "bonded_tokens,supply"
+	nominalAPRInputs   = "annual_provisions,community_tax,bonded_tokens"
+	realAPRInputs      = "annual_provisions,community_tax,bonded_tokens,inflation"
+	delegatorAPRInputs = "annual_provisions,community_tax,bonded_tokens,commission_rate"
+)
+
+// StakingEconomics holds the chain-wide values the staking yield is derived
+// from, all in base denom units except for the rates.
+type StakingEconomics struct {
+	BondDenom        string
+	BondedTokens     float64
+	Supply           float64
+	AnnualProvisions float64
+	CommunityTax     float64
+	Inflation        float64
+	// Minting is false on chains without x/mint, where there's no annual
+	// provisions and inflation to derive the APRs from.
+	Minting bool
+}
+
+func getStakingEconomics(ctx context.Context, grpcConn NodeConn) (*StakingEconomics, error) {
+	economics := &StakingEconomics{}
+
+	stakingClient := stakingtypes.NewQueryClient(grpcConn)
+	paramsResponse, err := stakingClient.Params(ctx, &stakingtypes.QueryParamsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	economics.BondDenom = paramsResponse.Params.BondDenom
+
+	poolResponse, err := stakingClient.Pool(ctx, &stakingtypes.QueryPoolRequest{})
+	if err != nil {
+		return nil, err
+	}
+	economics.BondedTokens, _ = new(big.Float).SetInt(poolResponse.Pool.BondedTokens.BigInt()).Float64()
+
+	bankClient := banktypes.NewQueryClient(grpcConn)
+	supplyResponse, err := bankClient.SupplyOf(ctx, &banktypes.QuerySupplyOfRequest{Denom: economics.BondDenom})
+	if err != nil {
+		return nil, err
+	}
+	economics.Supply, _ = new(big.Float).SetInt(supplyResponse.Amount.Amount.BigInt()).Float64()
+
+	if !capabilities.HasService(mintQueryService) {
+		return economics, nil
+	}
+	economics.Minting = true
+
+	mintClient := minttypes.NewQueryClient(grpcConn)
+	provisionsResponse, err := mintClient.AnnualProvisions(ctx, &minttypes.QueryAnnualProvisionsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if economics.AnnualProvisions, err = strconv.ParseFloat(provisionsResponse.AnnualProvisions.String(), 64); err != nil {
+		return nil, err
+	}
+
+	inflationResponse, err := mintClient.Inflation(ctx, &minttypes.QueryInflationRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if economics.Inflation, err = strconv.ParseFloat(inflationResponse.Inflation.String(), 64); err != nil {
+		return nil, err
+	}
+
+	if !capabilities.HasService(distributionQueryService) {
+		return economics, nil
+	}
+
+	distributionClient := distributiontypes.NewQueryClient(grpcConn)
+	distributionParamsResponse, err := distributionClient.Params(ctx, &distributiontypes.QueryParamsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if economics.CommunityTax, err = strconv.ParseFloat(distributionParamsResponse.Params.CommunityTax.String(), 64); err != nil {
+		return nil, err
+	}
+
+	return economics, nil
+}
+
+// BondedRatio is the share of the bond denom supply that is bonded.
+func (e *StakingEconomics) BondedRatio() float64 {
+	if e.Supply == 0 {
+		return 0
+	}
+
+	return e.BondedTokens / e.Supply
+}
+
+// NominalAPR is the yearly yield of the bonded tokens before inflation and
+// validators commission.
+func (e *StakingEconomics) NominalAPR() float64 {
+	if e.BondedTokens == 0 {
+		return 0
+	}
+
+	return e.AnnualProvisions * (1 - e.CommunityTax) / e.BondedTokens
+}
+
+// RealAPR is the nominal APR adjusted for the supply dilution by inflation.
+func (e *StakingEconomics) RealAPR() float64 {
+	return (1+e.NominalAPR())/(1+e.Inflation) - 1
+}
+
+// DelegatorAPR is the nominal APR a delegator gets after the validator takes
+// its commission.
+func (e *StakingEconomics) DelegatorAPR(commissionRate float64) float64 {
+	return e.NominalAPR() * (1 - commissionRate)
+}
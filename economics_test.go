@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStakingEconomics(t *testing.T) {
+	tests := []struct {
+		name           string
+		economics      StakingEconomics
+		commissionRate float64
+		bondedRatio    float64
+		nominalAPR     float64
+		realAPR        float64
+		delegatorAPR   float64
+	}{
+		{
+			name: "inflationary chain",
+			economics: StakingEconomics{
+				BondedTokens:     600,
+				Supply:           1000,
+				AnnualProvisions: 100,
+				CommunityTax:     0.02,
+				Inflation:        0.1,
+			},
+			commissionRate: 0.05,
+			bondedRatio:    0.6,
+			nominalAPR:     98.0 / 600,
+			realAPR:        (1+98.0/600)/1.1 - 1,
+			delegatorAPR:   98.0 / 600 * 0.95,
+		},
+		{
+			name: "no community tax or inflation",
+			economics: StakingEconomics{
+				BondedTokens:     500,
+				Supply:           500,
+				AnnualProvisions: 50,
+			},
+			commissionRate: 1,
+			bondedRatio:    1,
+			nominalAPR:     0.1,
+			realAPR:        0.1,
+			delegatorAPR:   0,
+		},
+		{
+			name:      "nothing bonded",
+			economics: StakingEconomics{AnnualProvisions: 50},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assertFloat(t, "bonded ratio", test.economics.BondedRatio(), test.bondedRatio)
+			assertFloat(t, "nominal APR", test.economics.NominalAPR(), test.nominalAPR)
+			assertFloat(t, "real APR", test.economics.RealAPR(), test.realAPR)
+			assertFloat(t, "delegator APR", test.economics.DelegatorAPR(test.commissionRate), test.delegatorAPR)
+		})
+	}
+}
+
+func assertFloat(t *testing.T, name string, actual, expected float64) {
+	t.Helper()
+
+	if math.Abs(actual-expected) > 1e-9 {
+		t.Errorf("%s is %v, expected %v", name, actual, expected)
+	}
+}
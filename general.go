@@ -1,14 +1,11 @@
 package main
 
 import (
-	"context"
 	"net/http"
 	"strconv"
 	"sync"
 	"time"
 
-	"google.golang.org/grpc"
-
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
 	minttypes "github.com/cosmos/cosmos-sdk/x/mint/types"
@@ -18,7 +15,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func GeneralHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.ClientConn) {
+func GeneralHandler(w http.ResponseWriter, r *http.Request, grpcConn NodeConn) {
 	requestStart := time.Now()
 
 	sublogger := log.With().
@@ -76,6 +73,88 @@ func GeneralHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Clien
 		[]string{"denoms"},
 	)
 
+	generalBondedRatioGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_general_bonded_ratio",
+			Help:        "Share of the bond denom supply that is bonded",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"denom", "inputs"},
+	)
+
+	generalNominalAPRGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_general_nominal_apr",
+			Help:        "Nominal staking APR, before inflation and validators commission",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"denom", "inputs"},
+	)
+
+	generalRealAPRGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_general_real_apr",
+			Help:        "Real staking APR, adjusted for inflation",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"denom", "inputs"},
+	)
+
+	generalUnbondingTokensGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_general_unbonding_tokens",
+			Help:        "Tokens being unbonded, by the number of days until completion",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"denom", "days"},
+	)
+
+	generalValidatorStakeFlowGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_general_validator_stake_flow",
+			Help:        "Net change of the validator tokens over the last refresh interval",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker", "denom"},
+	)
+
+	generalUnbondingQueueRefreshedGauge := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_general_unbonding_queue_refreshed_at",
+			Help:        "Unix timestamp of the last unbonding queue refresh",
+			ConstLabels: ConstLabels,
+		},
+	)
+
+	generalCirculatingSupplyGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_general_circulating_supply",
+			Help:        "Circulating supply",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"denom"},
+	)
+
+	generalCirculatingSupplyExcludedGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_general_circulating_supply_excluded",
+			Help:        "Amount subtracted from the total supply to get the circulating supply, by component",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"denom", "component"},
+	)
+
+	generalCirculatingSupplySkippedGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_general_circulating_supply_skipped_accounts",
+			Help:        "Number of accounts of an unknown type left out of the circulating supply",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"type"},
+	)
+
+	snapshotHeightGauge := newSnapshotHeightGauge()
+
 	registry := prometheus.NewRegistry()
 	registry.MustRegister(generalBondedTokensGauge)
 	registry.MustRegister(generalNotBondedTokensGauge)
@@ -83,6 +162,33 @@ func GeneralHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Clien
 	registry.MustRegister(generalSupplyTotalGauge)
 	registry.MustRegister(generalInflationGauge)
 	registry.MustRegister(generalAnnualProvisions)
+	registry.MustRegister(generalBondedRatioGauge)
+	registry.MustRegister(generalNominalAPRGauge)
+	registry.MustRegister(generalRealAPRGauge)
+	registry.MustRegister(generalUnbondingTokensGauge)
+	registry.MustRegister(generalValidatorStakeFlowGauge)
+	registry.MustRegister(generalUnbondingQueueRefreshedGauge)
+	registry.MustRegister(generalCirculatingSupplyGauge)
+	registry.MustRegister(generalCirculatingSupplyExcludedGauge)
+	registry.MustRegister(generalCirculatingSupplySkippedGauge)
+	registry.MustRegister(snapshotHeightGauge)
+
+	scrape := NewScrape(grpcConn)
+	ctx := scrape.Context()
+	setSnapshotHeight(snapshotHeightGauge, "scrape", scrape.Height)
+
+	unbondingQueue.Export(
+		generalUnbondingTokensGauge,
+		generalValidatorStakeFlowGauge,
+		generalUnbondingQueueRefreshedGauge,
+		snapshotHeightGauge,
+	)
+	circulatingSupply.Export(
+		generalCirculatingSupplyGauge,
+		generalCirculatingSupplyExcludedGauge,
+		generalCirculatingSupplySkippedGauge,
+		snapshotHeightGauge,
+	)
 
 	var wg sync.WaitGroup
 
@@ -94,7 +200,7 @@ func GeneralHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Clien
 
 		stakingClient := stakingtypes.NewQueryClient(grpcConn)
 		response, err := stakingClient.Pool(
-			context.Background(),
+			ctx,
 			&stakingtypes.QueryPoolRequest{},
 		)
 		if err != nil {
@@ -113,12 +219,17 @@ func GeneralHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Clien
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if !capabilities.HasService(distributionQueryService) {
+			sublogger.Trace().Msg("Distribution module is not available, not querying community pool")
+			return
+		}
+
 		sublogger.Debug().Msg("Started querying distribution community pool")
 		queryStart := time.Now()
 
 		distributionClient := distributiontypes.NewQueryClient(grpcConn)
 		response, err := distributionClient.CommunityPool(
-			context.Background(),
+			ctx,
 			&distributiontypes.QueryCommunityPoolRequest{},
 		)
 		if err != nil {
@@ -151,7 +262,7 @@ func GeneralHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Clien
 
 		bankClient := banktypes.NewQueryClient(grpcConn)
 		response, err := bankClient.TotalSupply(
-			context.Background(),
+			ctx,
 			&banktypes.QueryTotalSupplyRequest{},
 		)
 		if err != nil {
@@ -179,12 +290,17 @@ func GeneralHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Clien
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if !capabilities.HasService(mintQueryService) {
+			sublogger.Trace().Msg("Mint module is not available, not querying inflation")
+			return
+		}
+
 		sublogger.Debug().Msg("Started querying inflation")
 		queryStart := time.Now()
 
 		mintClient := minttypes.NewQueryClient(grpcConn)
 		response, err := mintClient.Inflation(
-			context.Background(),
+			ctx,
 			&minttypes.QueryInflationRequest{},
 		)
 		if err != nil {
@@ -208,12 +324,17 @@ func GeneralHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Clien
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if !capabilities.HasService(mintQueryService) {
+			sublogger.Trace().Msg("Mint module is not available, not querying annual provisions")
+			return
+		}
+
 		sublogger.Debug().Msg("Started querying annual provisions")
 		queryStart := time.Now()
 
 		mintClient := minttypes.NewQueryClient(grpcConn)
 		response, err := mintClient.AnnualProvisions(
-			context.Background(),
+			ctx,
 			&minttypes.QueryAnnualProvisionsRequest{},
 		)
 		if err != nil {
@@ -231,14 +352,50 @@ func GeneralHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Clien
 				Msg("Could not parse annual provisions")
 		} else {
 			generalAnnualProvisions.With(prometheus.Labels{
-				"denom": Denom,
+				"denoms": Denom,
 			}).Set(value / DenomCoefficient)
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sublogger.Debug().Msg("Started querying staking economics")
+		queryStart := time.Now()
+
+		economics, err := getStakingEconomics(ctx, grpcConn)
+		if err != nil {
+			sublogger.Error().Err(err).Msg("Could not get staking economics")
+			return
+		}
+
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying staking economics")
+
+		generalBondedRatioGauge.With(prometheus.Labels{
+			"denom":  economics.BondDenom,
+			"inputs": bondedRatioInputs,
+		}).Set(economics.BondedRatio())
+
+		if !economics.Minting {
+			return
+		}
+
+		generalNominalAPRGauge.With(prometheus.Labels{
+			"denom":  economics.BondDenom,
+			"inputs": nominalAPRInputs,
+		}).Set(economics.NominalAPR())
+
+		generalRealAPRGauge.With(prometheus.Labels{
+			"denom":  economics.BondDenom,
+			"inputs": realAPRInputs,
+		}).Set(economics.RealAPR())
+	}()
+
 	wg.Wait()
 
-	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	h := promhttp.HandlerFor(withValues(registry), promhttp.HandlerOpts{})
 	h.ServeHTTP(w, r)
 	sublogger.Info().
 		Str("method", "GET").
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+// genericQueryMethod is a generic query method resolved through the server
+// reflection, with the types needed to encode and decode its messages.
+type genericQueryMethod struct {
+	Method protoreflect.MethodDescriptor
+	Types  *dynamicpb.Types
+}
+
+// GenericQueryResolver resolves the generic query methods on their first use
+// and keeps them, so the server reflection is only used once per method. A
+// method that could not be resolved is tried again on the next scrape.
+type GenericQueryResolver struct {
+	mutex   sync.Mutex
+	methods map[string]*genericQueryMethod
+}
+
+var genericQueryResolver = &GenericQueryResolver{
+	methods: map[string]*genericQueryMethod{},
+}
+
+func (r *GenericQueryResolver) Resolve(
+	ctx context.Context,
+	grpcConn NodeConn,
+	service string,
+	method string,
+) (*genericQueryMethod, error) {
+	fullName := service + "/" + method
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if resolved, ok := r.methods[fullName]; ok {
+		return resolved, nil
+	}
+
+	fileDescriptors, err := getFileDescriptors(ctx, grpcConn, service)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := protodesc.FileOptions{AllowUnresolvable: true}.NewFiles(fileDescriptors)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, err
+	}
+
+	serviceDescriptor, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+
+	methodDescriptor := serviceDescriptor.Methods().ByName(protoreflect.Name(method))
+	if methodDescriptor == nil {
+		return nil, fmt.Errorf("service %s has no method %s", service, method)
+	}
+
+	if methodDescriptor.IsStreamingClient() || methodDescriptor.IsStreamingServer() {
+		return nil, fmt.Errorf("streaming method %s is not supported", fullName)
+	}
+
+	resolved := &genericQueryMethod{
+		Method: methodDescriptor,
+		Types:  dynamicpb.NewTypes(files),
+	}
+	r.methods[fullName] = resolved
+
+	return resolved, nil
+}
+
+// GenericQueriesHandler exports the metrics of all the generic queries from
+// the config.
+func GenericQueriesHandler(w http.ResponseWriter, r *http.Request, grpcConn NodeConn) {
+	requestStart := time.Now()
+
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	querySuccessGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_exporter_query_success",
+			Help:        "1 if the generic query succeeded and its metrics were extracted, 0 if not",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"query"},
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(querySuccessGauge)
+
+	scrape := NewScrape(grpcConn)
+	gatherers := prometheus.Gatherers{registry, scrape.Gatherer()}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, query := range GenericQueries {
+		wg.Add(1)
+		go func(query GenericQuery) {
+			defer wg.Done()
+
+			queryRegistry := prometheus.NewRegistry()
+			err := collectGenericQueryMetrics(queryRegistry, scrape, query, sublogger)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err != nil {
+				querySuccessGauge.With(prometheus.Labels{"query": query.Name}).Set(0)
+				return
+			}
+
+			querySuccessGauge.With(prometheus.Labels{"query": query.Name}).Set(1)
+			gatherers = append(gatherers, queryRegistry)
+		}(query)
+	}
+
+	wg.Wait()
+
+	h := promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
+		ErrorLog:      &sublogger,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+	h.ServeHTTP(w, r)
+	sublogger.Info().
+		Str("method", "GET").
+		Str("endpoint", "/metrics/queries").
+		Int("queries", len(GenericQueries)).
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
+
+func collectGenericQueryMetrics(
+	registry prometheus.Registerer,
+	scrape *Scrape,
+	query GenericQuery,
+	sublogger zerolog.Logger,
+) error {
+	sublogger.Debug().
+		Str("query", query.Name).
+		Msg("Started querying generic query")
+	queryStart := time.Now()
+
+	grpcConn := scrape.GrpcConn
+	ctx := scrape.Context()
+
+	resolved, err := genericQueryResolver.Resolve(ctx, grpcConn, query.Service, query.Method)
+	if err != nil {
+		sublogger.Error().
+			Str("query", query.Name).
+			Err(err).
+			Msg("Could not resolve generic query method")
+		return err
+	}
+
+	request := dynamicpb.NewMessage(resolved.Method.Input())
+	if query.Request != "" {
+		if err := (protojson.UnmarshalOptions{Resolver: resolved.Types}).Unmarshal([]byte(query.Request), request); err != nil {
+			sublogger.Error().
+				Str("query", query.Name).
+				Err(err).
+				Msg("Could not parse generic query request")
+			return err
+		}
+	}
+
+	response := dynamicpb.NewMessage(resolved.Method.Output())
+	if err := grpcConn.Invoke(ctx, "/"+query.Service+"/"+query.Method, request, response); err != nil {
+		sublogger.Error().
+			Str("query", query.Name).
+			Err(err).
+			Msg("Could not get generic query response")
+		return err
+	}
+
+	sublogger.Debug().
+		Str("query", query.Name).
+		Float64("request-time", time.Since(queryStart).Seconds()).
+		Msg("Finished querying generic query")
+
+	responseJSON, err := protojson.MarshalOptions{
+		UseProtoNames:   true,
+		EmitUnpopulated: true,
+		Resolver:        resolved.Types,
+	}.Marshal(response)
+	if err != nil {
+		sublogger.Error().
+			Str("query", query.Name).
+			Err(err).
+			Msg("Could not encode generic query response")
+		return err
+	}
+
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(responseJSON))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		sublogger.Error().
+			Str("query", query.Name).
+			Err(err).
+			Msg("Could not decode generic query response")
+		return err
+	}
+
+	for _, metric := range query.Metrics {
+		labelNames := make([]string, 0, len(metric.Labels))
+		for label := range metric.Labels {
+			labelNames = append(labelNames, label)
+		}
+
+		help := metric.Help
+		if help == "" {
+			help = fmt.Sprintf("Extracted from the %s query", query.Name)
+		}
+
+		gauge := prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:        metric.Name,
+				Help:        help,
+				ConstLabels: ConstLabels,
+			},
+			labelNames,
+		)
+
+		if err := registry.Register(gauge); err != nil {
+			sublogger.Error().
+				Str("query", query.Name).
+				Str("metric", metric.Name).
+				Err(err).
+				Msg("Could not register generic query metric")
+			return err
+		}
+
+		divisor := metric.Divisor
+		if divisor == 0 {
+			divisor = 1
+		}
+
+		for _, element := range getJSONPath(document, metric.Each) {
+			value := float64(1)
+			if metric.Value != "" {
+				values := getJSONPath(element, metric.Value)
+				if len(values) == 0 {
+					continue
+				}
+
+				if value, err = getJSONFloat(values[0]); err != nil {
+					sublogger.Error().
+						Str("query", query.Name).
+						Str("metric", metric.Name).
+						Err(err).
+						Msg("Could not parse generic query value")
+					continue
+				}
+			}
+
+			labels := make(prometheus.Labels, len(metric.Labels))
+			for label, path := range metric.Labels {
+				labels[label] = ""
+				if labelValues := getJSONPath(element, path); len(labelValues) > 0 {
+					labels[label] = getJSONString(labelValues[0])
+				}
+			}
+
+			gauge.With(labels).Set(value / divisor)
+		}
+	}
+
+	return nil
+}
+
+// getJSONPath returns the values at the dot-separated path, going through
+// every element of the lists on the way. An empty path is the value itself.
+func getJSONPath(value interface{}, path string) []interface{} {
+	values := []interface{}{value}
+	if path == "" {
+		return flattenJSONLists(values)
+	}
+
+	for _, field := range strings.Split(path, ".") {
+		var next []interface{}
+		for _, current := range flattenJSONLists(values) {
+			if object, ok := current.(map[string]interface{}); ok {
+				if fieldValue, ok := object[field]; ok {
+					next = append(next, fieldValue)
+				}
+			}
+		}
+		values = next
+	}
+
+	return flattenJSONLists(values)
+}
+
+func flattenJSONLists(values []interface{}) []interface{} {
+	var flattened []interface{}
+	for _, value := range values {
+		if list, ok := value.([]interface{}); ok {
+			flattened = append(flattened, flattenJSONLists(list)...)
+		} else {
+			flattened = append(flattened, value)
+		}
+	}
+
+	return flattened
+}
+
+// getJSONFloat parses the value as a number. Big integers and decimals are
+// encoded as strings in the protobuf JSON format.
+func getJSONFloat(value interface{}) (float64, error) {
+	switch typed := value.(type) {
+	case json.Number:
+		return typed.Float64()
+	case string:
+		return strconv.ParseFloat(typed, 64)
+	case bool:
+		if typed {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("value of type %T is not a number", value)
+	}
+}
+
+func getJSONString(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case json.Number:
+		return typed.String()
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", typed)
+	}
+}
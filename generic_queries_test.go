@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestGetJSONPath(t *testing.T) {
+	document := `{
+		"pool": {"bonded_tokens": "100", "not_bonded_tokens": "5"},
+		"balances": [
+			{"denom": "uxprt", "amount": "10"},
+			{"denom": "uatom", "amount": "20"}
+		],
+		"nested": [[{"value": 1}], [{"value": 2}, {"other": 3}]],
+		"enabled": true
+	}`
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(document)))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []interface{}
+	}{
+		{name: "nested object", path: "pool.bonded_tokens", expected: []interface{}{"100"}},
+		{name: "through a list", path: "balances.amount", expected: []interface{}{"10", "20"}},
+		{name: "through nested lists", path: "nested.value", expected: []interface{}{json.Number("1"), json.Number("2")}},
+		{name: "boolean", path: "enabled", expected: []interface{}{true}},
+		{name: "missing field", path: "pool.missing"},
+		{name: "field of a scalar", path: "enabled.value"},
+		{name: "list itself", path: "balances.denom", expected: []interface{}{"uxprt", "uatom"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if values := getJSONPath(value, test.path); !reflect.DeepEqual(values, test.expected) {
+				t.Errorf("got %#v, expected %#v", values, test.expected)
+			}
+		})
+	}
+
+	t.Run("empty path", func(t *testing.T) {
+		values := getJSONPath([]interface{}{json.Number("1"), []interface{}{json.Number("2")}}, "")
+		expected := []interface{}{json.Number("1"), json.Number("2")}
+		if !reflect.DeepEqual(values, expected) {
+			t.Errorf("got %#v, expected %#v", values, expected)
+		}
+	})
+}
+
+func TestGetJSONFloat(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected float64
+		err      bool
+	}{
+		{value: json.Number("1.5"), expected: 1.5},
+		{value: "1000000000000", expected: 1e12},
+		{value: true, expected: 1},
+		{value: false, expected: 0},
+		{value: "abc", err: true},
+		{value: nil, err: true},
+	}
+
+	for _, test := range tests {
+		value, err := getJSONFloat(test.value)
+		if (err != nil) != test.err {
+			t.Errorf("%#v: got error %v, expected error %t", test.value, err, test.err)
+		}
+
+		if err == nil && value != test.expected {
+			t.Errorf("%#v: got %f, expected %f", test.value, value, test.expected)
+		}
+	}
+}
@@ -10,11 +10,9 @@ import (
 	"os"
 	"time"
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
@@ -28,11 +26,25 @@ var (
 	Denom         string
 	ListenAddress string
 	NodeAddress   string
+	Backend       string
+	LCDAddress    string
 	TendermintRPC string
 	LogLevel      string
 	JsonOutput    bool
 	Limit         uint64
 
+	RefreshInterval      time.Duration
+	PriceRefreshInterval time.Duration
+	PriceMaxAge          time.Duration
+	PriceValueMetrics    bool
+	AlertInterval        time.Duration
+	MaxConcurrentTargets int
+	ServiceDiscoveryHost string
+
+	CirculatingSupplyEnabled           bool
+	CirculatingSupplyExcludedAddresses []string
+	CirculatingSupplyIncludedModules   []string
+
 	Prefix                    string
 	AccountPrefix             string
 	AccountPubkeyPrefix       string
@@ -42,6 +54,7 @@ var (
 	ConsensusNodePubkeyPrefix string
 
 	ChainID          string
+	BondDenom        string
 	ConstLabels      map[string]string
 	DenomCoefficient float64
 	DenomExponent    uint64
@@ -69,6 +82,15 @@ var rootCmd = &cobra.Command{
 		cmd.Flags().VisitAll(func(f *pflag.Flag) {
 			if !f.Changed && viper.IsSet(f.Name) {
 				val := viper.Get(f.Name)
+				if values, ok := val.([]interface{}); ok {
+					for _, value := range values {
+						if err := cmd.Flags().Set(f.Name, fmt.Sprintf("%v", value)); err != nil {
+							log.Fatal().Err(err).Msg("Could not set flag")
+						}
+					}
+					return
+				}
+
 				if err := cmd.Flags().Set(f.Name, fmt.Sprintf("%v", val)); err != nil {
 					log.Fatal().Err(err).Msg("Could not set flag")
 				}
@@ -120,16 +142,7 @@ func setBechPrefixes(cmd *cobra.Command) {
 }
 
 func Execute(cmd *cobra.Command, args []string) {
-	logLevel, err := zerolog.ParseLevel(LogLevel)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Could not parse log level")
-	}
-
-	if JsonOutput {
-		log = zerolog.New(os.Stdout).With().Timestamp().Logger()
-	}
-
-	zerolog.SetGlobalLevel(logLevel)
+	setLogger()
 
 	log.Info().
 		Str("--bech-account-prefix", AccountPrefix).
@@ -143,27 +156,69 @@ func Execute(cmd *cobra.Command, args []string) {
 		Str("--denom-exponent", fmt.Sprintf("%d", DenomExponent)).
 		Str("--listen-address", ListenAddress).
 		Str("--node", NodeAddress).
+		Str("--backend", Backend).
+		Str("--lcd", LCDAddress).
 		Str("--log-level", LogLevel).
+		Dur("--refresh-interval", RefreshInterval).
 		Msg("Started with following parameters")
 
-	config := sdk.GetConfig()
-	config.SetBech32PrefixForAccount(AccountPrefix, AccountPubkeyPrefix)
-	config.SetBech32PrefixForValidator(ValidatorPrefix, ValidatorPubkeyPrefix)
-	config.SetBech32PrefixForConsensusNode(ConsensusNodePrefix, ConsensusNodePubkeyPrefix)
-	config.Seal()
-
-	grpcConn, err := grpc.Dial(
-		NodeAddress,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Could not connect to gRPC node")
+	if err := loadConfigSections(); err != nil {
+		log.Fatal().Err(err).Msg("Could not parse config")
 	}
+
+	checkMaxConcurrentTargets()
+	setBechConfig()
+
+	grpcConn := dialNode()
 	defer grpcConn.Close()
 
 	setChainID()
+	capabilities = probeCapabilities(grpcConn)
+
 	setDenom(grpcConn)
 
+	runPeriodically("unbonding-queue", RefreshInterval, func() error {
+		return unbondingQueue.Refresh(grpcConn)
+	})
+
+	if len(OperationalWallets) > 0 {
+		runPeriodically("operational-wallets", RefreshInterval, func() error {
+			return operationalWalletsMonitor.Refresh(grpcConn)
+		})
+	}
+
+	if len(ValidatorTargets) > 0 || len(WalletTargets) > 0 {
+		runPeriodically("accrual", RefreshInterval, func() error {
+			return accrualTracker.Refresh(grpcConn)
+		})
+	}
+
+	if len(PriceSources) > 0 {
+		priceCache = NewPriceCache(PriceSources)
+		runPeriodically("prices", PriceRefreshInterval, priceCache.Refresh)
+	}
+
+	if len(AlertRules) > 0 {
+		alertEngine = NewAlertEngine(AlertNotifiers)
+		runPeriodically("alerts", AlertInterval, func() error {
+			return alertEngine.Evaluate(grpcConn)
+		})
+	}
+
+	if CirculatingSupplyEnabled {
+		runPeriodically("circulating-supply", RefreshInterval, func() error {
+			return circulatingSupply.Refresh(grpcConn)
+		})
+	}
+
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		MetricsHandler(w, r, grpcConn)
+	})
+
+	http.HandleFunc("/sd", func(w http.ResponseWriter, r *http.Request) {
+		ServiceDiscoveryHandler(w, r, grpcConn)
+	})
+
 	http.HandleFunc("/metrics/wallet", func(w http.ResponseWriter, r *http.Request) {
 		WalletHandler(w, r, grpcConn)
 	})
@@ -184,13 +239,66 @@ func Execute(cmd *cobra.Command, args []string) {
 		GeneralHandler(w, r, grpcConn)
 	})
 
+	http.HandleFunc("/metrics/portfolio", func(w http.ResponseWriter, r *http.Request) {
+		PortfolioHandler(w, r, grpcConn)
+	})
+
+	http.HandleFunc("/metrics/accrual", func(w http.ResponseWriter, r *http.Request) {
+		AccrualHandler(w, r)
+	})
+
+	http.HandleFunc("/metrics/prices", func(w http.ResponseWriter, r *http.Request) {
+		PricesHandler(w, r)
+	})
+
+	http.HandleFunc("/metrics/alerts", func(w http.ResponseWriter, r *http.Request) {
+		AlertsHandler(w, r)
+	})
+
+	http.HandleFunc("/metrics/queries", func(w http.ResponseWriter, r *http.Request) {
+		GenericQueriesHandler(w, r, grpcConn)
+	})
+
+	http.HandleFunc("/metrics/capabilities", func(w http.ResponseWriter, r *http.Request) {
+		CapabilitiesHandler(w, r)
+	})
+
+	http.HandleFunc("/metrics/operational-wallets", func(w http.ResponseWriter, r *http.Request) {
+		OperationalWalletsHandler(w, r)
+	})
+
+	http.HandleFunc("/dashboards/", func(w http.ResponseWriter, r *http.Request) {
+		DashboardsHandler(w, r)
+	})
+
 	log.Info().Str("address", ListenAddress).Msg("Listening")
-	err = http.ListenAndServe(ListenAddress, nil)
+	err := http.ListenAndServe(ListenAddress, nil)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Could not start application")
 	}
 }
 
+func setLogger() {
+	logLevel, err := zerolog.ParseLevel(LogLevel)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Could not parse log level")
+	}
+
+	if JsonOutput {
+		log = zerolog.New(os.Stdout).With().Timestamp().Logger()
+	}
+
+	zerolog.SetGlobalLevel(logLevel)
+}
+
+func setBechConfig() {
+	config := sdk.GetConfig()
+	config.SetBech32PrefixForAccount(AccountPrefix, AccountPubkeyPrefix)
+	config.SetBech32PrefixForValidator(ValidatorPrefix, ValidatorPubkeyPrefix)
+	config.SetBech32PrefixForConsensusNode(ConsensusNodePrefix, ConsensusNodePubkeyPrefix)
+	config.Seal()
+}
+
 func setChainID() {
 	// Создаем HTTP клиент
 	client := &http.Client{
@@ -247,7 +355,18 @@ func setChainID() {
 	}
 }
 
-func setDenom(grpcConn *grpc.ClientConn) {
+// setDenom sets the bond denom the node returns the staked amounts in, and
+// the display denom and its decimals the amounts are exported in.
+func setDenom(grpcConn NodeConn) {
+	setStakingBondDenom(grpcConn)
+	setDisplayDenom(grpcConn)
+
+	if BondDenom == "" {
+		BondDenom = Denom
+	}
+}
+
+func setDisplayDenom(grpcConn NodeConn) {
 	if isUserProvidedAndHandled := checkAndHandleDenomInfoProvidedByUser(); isUserProvidedAndHandled {
 		return
 	}
@@ -258,11 +377,15 @@ func setDenom(grpcConn *grpc.ClientConn) {
 		&banktypes.QueryDenomsMetadataRequest{},
 	)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Error querying denom")
+		log.Warn().Err(err).Msg("Could not get denom metadata, falling back to the bond denom")
+		setBondDenom(grpcConn)
+		return
 	}
 
 	if len(denoms.Metadatas) == 0 {
-		log.Fatal().Msg("No denom infos. Try running the binary with --denom and --denom-coefficient to set them manually.")
+		log.Warn().Msg("No denom metadata, falling back to the bond denom")
+		setBondDenom(grpcConn)
+		return
 	}
 
 	metadata := denoms.Metadatas[0]
@@ -288,6 +411,65 @@ func setDenom(grpcConn *grpc.ClientConn) {
 	log.Fatal().Msg("Could not find the denom info")
 }
 
+// setBondDenom uses the staking bond denom when the chain has no denom
+// metadata to get the display denom and its decimals from.
+func setBondDenom(grpcConn NodeConn) {
+	if Denom == "" {
+		if BondDenom == "" {
+			log.Fatal().Msg("Could not get the bond denom. Try running the binary with --denom and --denom-coefficient to set them manually.")
+		}
+
+		Denom = BondDenom
+	}
+
+	if DenomExponent != 0 {
+		DenomCoefficient = math.Pow10(int(DenomExponent))
+	}
+
+	log.Info().
+		Str("denom", Denom).
+		Float64("coefficient", DenomCoefficient).
+		Msg("Using the bond denom. Run the binary with --denom-coefficient or --denom-exponent to set its decimals.")
+}
+
+// setStakingBondDenom gets the base denom of the stake, the one the node
+// returns the staked amounts in, as opposed to the display Denom they're
+// exported in. It's the display Denom itself if it can't be got.
+func setStakingBondDenom(grpcConn NodeConn) {
+	stakingClient := stakingtypes.NewQueryClient(grpcConn)
+	response, err := stakingClient.Params(
+		context.Background(),
+		&stakingtypes.QueryParamsRequest{},
+	)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not get the bond denom")
+		return
+	}
+
+	BondDenom = response.Params.BondDenom
+}
+
+// checkMaxConcurrentTargets makes sure the targets can be queried at all, as
+// none would ever get through the semaphore with a limit below 1.
+func checkMaxConcurrentTargets() {
+	if MaxConcurrentTargets < 1 {
+		log.Fatal().
+			Int("max-concurrent-targets", MaxConcurrentTargets).
+			Msg("--max-concurrent-targets must be at least 1")
+	}
+}
+
+// getDisplayAmount converts an amount of the bond denom to the display denom
+// the metrics are exported in. Only the decimals of the bond denom are
+// known, so the amounts of the other denoms are left in their base units.
+func getDisplayAmount(denom string, amount float64) float64 {
+	if denom != BondDenom {
+		return amount
+	}
+
+	return amount / DenomCoefficient
+}
+
 func checkAndHandleDenomInfoProvidedByUser() bool {
 	if Denom != "" {
 		if DenomCoefficient != 1 && DenomExponent != 0 {
@@ -325,8 +507,20 @@ func main() {
 	rootCmd.PersistentFlags().Uint64Var(&DenomExponent, "denom-exponent", 0, "Denom exponent")
 	rootCmd.PersistentFlags().StringVar(&ListenAddress, "listen-address", ":9300", "The address this exporter would listen on")
 	rootCmd.PersistentFlags().StringVar(&NodeAddress, "node", "localhost:9090", "RPC node address")
+	rootCmd.PersistentFlags().StringVar(&Backend, "backend", "grpc", "How to query the node: grpc, or rest to use the LCD REST API at --lcd")
+	rootCmd.PersistentFlags().StringVar(&LCDAddress, "lcd", "http://localhost:1317", "LCD REST API address, used with --backend=rest")
 	rootCmd.PersistentFlags().StringVar(&LogLevel, "log-level", "info", "Logging level")
 	rootCmd.PersistentFlags().Uint64Var(&Limit, "limit", 1000, "Pagination limit for gRPC requests")
+	rootCmd.PersistentFlags().DurationVar(&RefreshInterval, "refresh-interval", 5*time.Minute, "Interval between background refreshes of the chain-wide data")
+	rootCmd.PersistentFlags().DurationVar(&PriceRefreshInterval, "price-refresh-interval", time.Minute, "Interval between the refreshes of the prices from the config")
+	rootCmd.PersistentFlags().DurationVar(&PriceMaxAge, "price-max-age", 15*time.Minute, "Age after which a price is stale and no longer used to value the amounts")
+	rootCmd.PersistentFlags().BoolVar(&PriceValueMetrics, "price-value-metrics", false, "Export the _value_usd companions of the wallet, validator and general token amounts")
+	rootCmd.PersistentFlags().DurationVar(&AlertInterval, "alert-interval", time.Minute, "Interval between the evaluations of the alert rules from the config")
+	rootCmd.PersistentFlags().IntVar(&MaxConcurrentTargets, "max-concurrent-targets", 4, "How many validators and wallets from the config are queried at once on /metrics")
+	rootCmd.PersistentFlags().StringVar(&ServiceDiscoveryHost, "sd-host", "", "Exporter host:port returned as the target on /sd, defaults to the host the request was sent to")
+	rootCmd.PersistentFlags().BoolVar(&CirculatingSupplyEnabled, "circulating-supply", false, "Compute the circulating supply in the background")
+	rootCmd.PersistentFlags().StringSliceVar(&CirculatingSupplyExcludedAddresses, "circulating-supply-excluded-addresses", []string{}, "Treasury and foundation addresses to subtract from the circulating supply")
+	rootCmd.PersistentFlags().StringSliceVar(&CirculatingSupplyIncludedModules, "circulating-supply-included-modules", []string{"bonded_tokens_pool", "not_bonded_tokens_pool"}, "Module accounts whose balances are not subtracted from the circulating supply")
 	rootCmd.PersistentFlags().StringVar(&TendermintRPC, "tendermint-rpc", "http://localhost:26657", "Tendermint RPC address")
 	rootCmd.PersistentFlags().BoolVar(&JsonOutput, "json", false, "Output logs as JSON")
 
@@ -338,6 +532,38 @@ func main() {
 	rootCmd.PersistentFlags().StringVar(&ConsensusNodePrefix, "bech-consensus-node-prefix", "", "Bech32 consensus node prefix")
 	rootCmd.PersistentFlags().StringVar(&ConsensusNodePubkeyPrefix, "bech-consensus-node-pubkey-prefix", "", "Bech32 pubkey consensus node prefix")
 
+	backfillCmd.Flags().Int64Var(&BackfillFromHeight, "from-height", 0, "First height to backfill")
+	backfillCmd.Flags().Int64Var(&BackfillToHeight, "to-height", 0, "Last height to backfill, defaults to the latest one")
+	backfillCmd.Flags().Int64Var(&BackfillStep, "step", 1000, "Number of blocks between the backfilled heights")
+	backfillCmd.Flags().StringVar(&BackfillOutput, "output", "backfill.om", "OpenMetrics file to write")
+	backfillCmd.Flags().StringSliceVar(&BackfillValidators, "validator", []string{}, "Validator addresses to backfill along with the ones from the config")
+	backfillCmd.Flags().StringSliceVar(&BackfillWallets, "wallet", []string{}, "Wallet addresses to backfill along with the ones from the config")
+
+	reportCmd.Flags().StringVar(&ReportFromDate, "from-date", "", "First day to report on, as YYYY-MM-DD in UTC")
+	reportCmd.Flags().StringVar(&ReportToDate, "to-date", "", "Last day to report on, as YYYY-MM-DD in UTC, defaults to --from-date")
+	reportCmd.Flags().Int64Var(&ReportFromHeight, "from-height", 0, "Height to report from, instead of --from-date")
+	reportCmd.Flags().Int64Var(&ReportToHeight, "to-height", 0, "Height to report to, used with --from-height, defaults to the latest one")
+	reportCmd.Flags().StringVar(&ReportFormat, "format", "csv", "Ledger format: csv or json")
+	reportCmd.Flags().StringVar(&ReportOutput, "output", "rewards.csv", "Ledger file to write")
+	reportCmd.Flags().StringVar(&ReportPrices, "prices", "", "CSV file with the date, denom and price on every line, to value the earnings with")
+	reportCmd.Flags().StringSliceVar(&ReportValidators, "validator", []string{}, "Validator addresses to report the commission of, along with the ones from the config")
+	reportCmd.Flags().StringSliceVar(&ReportWallets, "wallet", []string{}, "Wallet addresses to report the rewards of, along with the ones from the config")
+
+	rulesCmd.Flags().StringVar(&RulesChain, "chain", "", "chain_id to limit the rules to, all chains if not set")
+	rulesCmd.Flags().StringSliceVar(&RulesValidators, "validator", []string{}, "Validator operator addresses to limit the alerts to, all the exported ones if not set")
+	rulesCmd.Flags().Float64Var(&RulesMissedBlocksRatio, "missed-blocks-ratio", 0.05, "Share of the signing window a validator can miss before it's alerted about")
+	rulesCmd.Flags().Float64Var(&RulesJailMargin, "jail-margin", 0.8, "Share of the downtime jailing threshold a validator is alerted about as about to be jailed at")
+	rulesCmd.Flags().IntVar(&RulesRankMargin, "rank-margin", 5, "How many places above the last active one a validator is alerted about as about to be dropped from the active set")
+	rulesCmd.Flags().StringVar(&RulesOutput, "output", "", "Rules file to write, stdout if not set")
+
+	dashboardsCmd.Flags().StringVar(&DashboardsOutput, "output", dashboardsDir, "Directory to write the dashboards to")
+
+	rootCmd.AddCommand(addressCmd)
+	rootCmd.AddCommand(backfillCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(rulesCmd)
+	rootCmd.AddCommand(dashboardsCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal().Err(err).Msg("Could not start application")
 	}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"time"
+)
+
+// maturityPeriods are the windows the unbonding and redelegation amounts
+// are bucketed into. A zero duration means no upper limit, i.e. the whole
+// unbonding period.
+var maturityPeriods = []struct {
+	Label    string
+	Duration time.Duration
+}{
+	{Label: "1d", Duration: 24 * time.Hour},
+	{Label: "7d", Duration: 7 * 24 * time.Hour},
+	{Label: "all", Duration: 0},
+}
+
+type maturingEntry struct {
+	CompletionTime time.Time
+	Amount         float64
+}
+
+// getMaturingAmounts sums the entries completing within each of the maturity
+// periods and returns the sums alongside the earliest completion time.
+func getMaturingAmounts(entries []maturingEntry, now time.Time) (map[string]float64, time.Time) {
+	amounts := make(map[string]float64, len(maturityPeriods))
+	var nextCompletion time.Time
+
+	for _, period := range maturityPeriods {
+		amounts[period.Label] = 0
+	}
+
+	for _, entry := range entries {
+		for _, period := range maturityPeriods {
+			if period.Duration == 0 || entry.CompletionTime.Sub(now) <= period.Duration {
+				amounts[period.Label] += entry.Amount
+			}
+		}
+
+		if nextCompletion.IsZero() || entry.CompletionTime.Before(nextCompletion) {
+			nextCompletion = entry.CompletionTime
+		}
+	}
+
+	return amounts, nextCompletion
+}
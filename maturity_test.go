@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetMaturingAmounts(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		entries        []maturingEntry
+		amounts        map[string]float64
+		nextCompletion time.Time
+	}{
+		{
+			name:    "no entries",
+			amounts: map[string]float64{"1d": 0, "7d": 0, "all": 0},
+		},
+		{
+			name: "bucketed by completion time",
+			entries: []maturingEntry{
+				{CompletionTime: now.Add(10 * 24 * time.Hour), Amount: 100},
+				{CompletionTime: now.Add(12 * time.Hour), Amount: 1},
+				{CompletionTime: now.Add(3 * 24 * time.Hour), Amount: 10},
+			},
+			amounts:        map[string]float64{"1d": 1, "7d": 11, "all": 111},
+			nextCompletion: now.Add(12 * time.Hour),
+		},
+		{
+			name: "completing exactly at the end of the period",
+			entries: []maturingEntry{
+				{CompletionTime: now.Add(24 * time.Hour), Amount: 5},
+			},
+			amounts:        map[string]float64{"1d": 5, "7d": 5, "all": 5},
+			nextCompletion: now.Add(24 * time.Hour),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			amounts, nextCompletion := getMaturingAmounts(test.entries, now)
+
+			for label, expected := range test.amounts {
+				if amounts[label] != expected {
+					t.Errorf("amount within %s is %f, expected %f", label, amounts[label], expected)
+				}
+			}
+
+			if !nextCompletion.Equal(test.nextCompletion) {
+				t.Errorf("next completion is %s, expected %s", nextCompletion, test.nextCompletion)
+			}
+		})
+	}
+}
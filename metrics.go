@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+type metricsCollector func(
+	registry prometheus.Registerer,
+	scrape *Scrape,
+	address string,
+	sublogger zerolog.Logger,
+) error
+
+// MetricsHandler exports the metrics of all the validators and wallets from
+// the config at once.
+func MetricsHandler(w http.ResponseWriter, r *http.Request, grpcConn NodeConn) {
+	requestStart := time.Now()
+
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	scrape := NewScrape(grpcConn)
+
+	var gatherers prometheus.Gatherers
+	gatherers = append(gatherers, collectTargets(ValidatorTargets, collectValidatorMetrics, scrape, sublogger)...)
+	gatherers = append(gatherers, collectTargets(WalletTargets, collectWalletMetrics, scrape, sublogger)...)
+	gatherers = append(gatherers, scrape.Gatherer())
+
+	h := promhttp.HandlerFor(withValues(gatherers), promhttp.HandlerOpts{
+		ErrorLog:      &sublogger,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+	h.ServeHTTP(w, r)
+	sublogger.Info().
+		Str("method", "GET").
+		Str("endpoint", "/metrics").
+		Int("validators", len(ValidatorTargets)).
+		Int("wallets", len(WalletTargets)).
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
+
+// collectTargets runs the collector for every target, at most
+// MaxConcurrentTargets of them at a time, each into its own registry with
+// the target labels added.
+func collectTargets(
+	targets []Target,
+	collector metricsCollector,
+	scrape *Scrape,
+	sublogger zerolog.Logger,
+) prometheus.Gatherers {
+	var gatherers prometheus.Gatherers
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	semaphore := make(chan struct{}, MaxConcurrentTargets)
+
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target Target) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			registry := prometheus.NewRegistry()
+			err := collector(
+				prometheus.WrapRegistererWith(target.Labels, registry),
+				scrape,
+				target.Address,
+				sublogger,
+			)
+			if err != nil {
+				return
+			}
+
+			mutex.Lock()
+			gatherers = append(gatherers, registry)
+			mutex.Unlock()
+		}(target)
+	}
+
+	wg.Wait()
+
+	return gatherers
+}
+
+// getAddresses returns the addresses passed to the request, either as
+// repeated address params or as comma-separated lists, without duplicates.
+func getAddresses(r *http.Request) []string {
+	var addresses []string
+	seen := map[string]bool{}
+
+	for _, param := range r.URL.Query()["address"] {
+		for _, address := range strings.Split(param, ",") {
+			address = strings.TrimSpace(address)
+			if address == "" || seen[address] {
+				continue
+			}
+
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	return addresses
+}
+
+func getTargets(addresses []string) []Target {
+	targets := make([]Target, len(addresses))
+	for index, address := range addresses {
+		targets[index] = Target{Address: address}
+	}
+
+	return targets
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGetAddresses(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		addresses []string
+	}{
+		{name: "none", query: ""},
+		{name: "single", query: "address=a", addresses: []string{"a"}},
+		{name: "repeated", query: "address=a&address=b", addresses: []string{"a", "b"}},
+		{name: "comma-separated", query: "address=a,b,%20c", addresses: []string{"a", "b", "c"}},
+		{name: "duplicates and empty", query: "address=a,,b&address=a&address=", addresses: []string{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/metrics/wallet?"+test.query, nil)
+			if addresses := getAddresses(request); !reflect.DeepEqual(addresses, test.addresses) {
+				t.Errorf("got %v, expected %v", addresses, test.addresses)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/cosmos/gogoproto/jsonpb"
+	gogoproto "github.com/cosmos/gogoproto/proto"
+)
+
+// NodeConn is what all the queries are sent through: either the gRPC
+// connection to the node, or the REST one when running with --backend=rest.
+type NodeConn interface {
+	grpc.ClientConnInterface
+	Close() error
+}
+
+// restTimeout bounds every query over the REST backend, so a stuck LCD
+// doesn't hang the scrapes and the background refreshes.
+const restTimeout = 30 * time.Second
+
+// restHeaderPrefix is the prefix the grpc-gateway adds to the gRPC response
+// metadata when passing it as HTTP headers.
+const restHeaderPrefix = "Grpc-Metadata-"
+
+// pathVariableRegexp matches the variables of a google.api.http path
+// template, such as {validator_addr} or {name=**}.
+var pathVariableRegexp = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?\}`)
+
+// RESTConn sends the gRPC queries to the LCD REST API of the node instead,
+// using the grpc-gateway routes declared in the google.api.http annotations
+// of the query services, so the query clients work unchanged over it.
+type RESTConn struct {
+	URL    string
+	Client *http.Client
+
+	mutex sync.Mutex
+	rules map[string]*annotations.HttpRule
+}
+
+func NewRESTConn(url string) *RESTConn {
+	return &RESTConn{
+		URL:    strings.TrimSuffix(url, "/"),
+		Client: &http.Client{Timeout: restTimeout},
+		rules:  map[string]*annotations.HttpRule{},
+	}
+}
+
+func (c *RESTConn) Invoke(
+	ctx context.Context,
+	method string,
+	args interface{},
+	reply interface{},
+	opts ...grpc.CallOption,
+) error {
+	request, ok := args.(gogoproto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "%s: request %T is not a protobuf message", method, args)
+	}
+
+	response, ok := reply.(gogoproto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "%s: response %T is not a protobuf message", method, reply)
+	}
+
+	rule, err := c.getHTTPRule(method)
+	if err != nil {
+		return err
+	}
+
+	httpMethod, pathTemplate := getHTTPRulePattern(rule)
+	if pathTemplate == "" {
+		return status.Errorf(codes.Unimplemented, "%s has no REST route", method)
+	}
+
+	fields, err := getRequestFields(request)
+	if err != nil {
+		return status.Errorf(codes.Internal, "%s: could not encode request: %s", method, err)
+	}
+
+	path := pathVariableRegexp.ReplaceAllStringFunc(pathTemplate, func(variable string) string {
+		name := pathVariableRegexp.FindStringSubmatch(variable)[1]
+		values := fields[name]
+		delete(fields, name)
+
+		if len(values) == 0 {
+			return ""
+		}
+
+		segments := strings.Split(values[0], "/")
+		for index, segment := range segments {
+			segments[index] = url.PathEscape(segment)
+		}
+
+		return strings.Join(segments, "/")
+	})
+
+	var body io.Reader
+	query := url.Values(fields)
+
+	if rule.Body != "" {
+		requestBody, err := (&jsonpb.Marshaler{OrigName: true, AnyResolver: interfaceRegistry}).MarshalToString(request)
+		if err != nil {
+			return status.Errorf(codes.Internal, "%s: could not encode request: %s", method, err)
+		}
+
+		body = strings.NewReader(requestBody)
+		query = url.Values{}
+	}
+
+	requestURL := c.URL + path
+	if encoded := query.Encode(); encoded != "" {
+		requestURL += "?" + encoded
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, httpMethod, requestURL, body)
+	if err != nil {
+		return status.Errorf(codes.Internal, "%s: %s", method, err)
+	}
+
+	if body != nil {
+		httpRequest.Header.Set("Content-Type", "application/json")
+	}
+
+	// The outgoing gRPC metadata, such as x-cosmos-block-height, is passed
+	// as headers, same as the grpc-gateway does the other way around.
+	if outgoing, ok := metadata.FromOutgoingContext(ctx); ok {
+		for key, values := range outgoing {
+			for _, value := range values {
+				httpRequest.Header.Add(key, value)
+			}
+		}
+	}
+
+	httpResponse, err := c.Client.Do(httpRequest)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "%s: %s", method, err)
+	}
+	defer httpResponse.Body.Close()
+
+	responseBody, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "%s: %s", method, err)
+	}
+
+	for _, opt := range opts {
+		if headerOpt, ok := opt.(grpc.HeaderCallOption); ok {
+			*headerOpt.HeaderAddr = getResponseMetadata(httpResponse.Header)
+		}
+	}
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return getRESTError(method, httpResponse.StatusCode, responseBody)
+	}
+
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true, AnyResolver: interfaceRegistry}
+	if err := unmarshaler.Unmarshal(bytes.NewReader(responseBody), response); err != nil {
+		return status.Errorf(codes.Internal, "%s: could not decode response: %s", method, err)
+	}
+
+	return nil
+}
+
+func (c *RESTConn) NewStream(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	method string,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	return nil, status.Errorf(codes.Unimplemented, "%s: streaming is not supported by the REST backend", method)
+}
+
+func (c *RESTConn) Close() error {
+	c.Client.CloseIdleConnections()
+	return nil
+}
+
+// getHTTPRule returns the google.api.http annotation of the gRPC method,
+// such as /cosmos.staking.v1beta1.Query/Validators.
+func (c *RESTConn) getHTTPRule(method string) (*annotations.HttpRule, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if rule, ok := c.rules[method]; ok {
+		return rule, nil
+	}
+
+	service, methodName, ok := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid method %s", method)
+	}
+
+	descriptor, err := gogoproto.HybridResolver.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, status.Errorf(codes.Unimplemented, "unknown service %s: %s", service, err)
+	}
+
+	serviceDescriptor, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "%s is not a service", service)
+	}
+
+	methodDescriptor := serviceDescriptor.Methods().ByName(protoreflect.Name(methodName))
+	if methodDescriptor == nil {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+
+	rule, ok := proto.GetExtension(methodDescriptor.Options(), annotations.E_Http).(*annotations.HttpRule)
+	if !ok || rule == nil {
+		return nil, status.Errorf(codes.Unimplemented, "%s has no REST route", method)
+	}
+
+	c.rules[method] = rule
+
+	return rule, nil
+}
+
+func getHTTPRulePattern(rule *annotations.HttpRule) (string, string) {
+	switch pattern := rule.Pattern.(type) {
+	case *annotations.HttpRule_Get:
+		return http.MethodGet, pattern.Get
+	case *annotations.HttpRule_Post:
+		return http.MethodPost, pattern.Post
+	case *annotations.HttpRule_Put:
+		return http.MethodPut, pattern.Put
+	case *annotations.HttpRule_Delete:
+		return http.MethodDelete, pattern.Delete
+	case *annotations.HttpRule_Patch:
+		return http.MethodPatch, pattern.Patch
+	default:
+		return "", ""
+	}
+}
+
+// getRequestFields flattens the set fields of the request into the dotted
+// field names the grpc-gateway accepts as the query params, for example
+// pagination.limit.
+func getRequestFields(request gogoproto.Message) (map[string][]string, error) {
+	encoded, err := (&jsonpb.Marshaler{OrigName: true, AnyResolver: interfaceRegistry}).MarshalToString(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var document interface{}
+	decoder := json.NewDecoder(strings.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+
+	fields := map[string][]string{}
+
+	var flatten func(prefix string, value interface{})
+	flatten = func(prefix string, value interface{}) {
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			for key, fieldValue := range typed {
+				if prefix == "" {
+					flatten(key, fieldValue)
+				} else {
+					flatten(prefix+"."+key, fieldValue)
+				}
+			}
+		case []interface{}:
+			for _, element := range typed {
+				flatten(prefix, element)
+			}
+		case nil:
+		default:
+			fields[prefix] = append(fields[prefix], getJSONString(typed))
+		}
+	}
+
+	flatten("", document)
+
+	return fields, nil
+}
+
+func getResponseMetadata(header http.Header) metadata.MD {
+	md := metadata.MD{}
+	for key, values := range header {
+		if name, ok := strings.CutPrefix(key, restHeaderPrefix); ok {
+			md.Append(strings.ToLower(name), values...)
+		}
+	}
+
+	return md
+}
+
+// getRESTError converts the grpc-gateway error response back to the gRPC
+// status it was made from.
+func getRESTError(method string, statusCode int, body []byte) error {
+	var errorResponse struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	if err := json.Unmarshal(body, &errorResponse); err != nil || errorResponse.Code == 0 {
+		return status.Errorf(codes.Unknown, "%s: unexpected status %d: %s", method, statusCode, strings.TrimSpace(string(body)))
+	}
+
+	return status.Error(codes.Code(errorResponse.Code), errorResponse.Message)
+}
+
+// dialNode connects to the node with the backend set by --backend.
+func dialNode() NodeConn {
+	switch Backend {
+	case "grpc":
+		grpcConn, err := grpc.Dial(
+			NodeAddress,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Could not connect to gRPC node")
+		}
+
+		return grpcConn
+	case "rest":
+		return NewRESTConn(LCDAddress)
+	default:
+		log.Fatal().Str("backend", Backend).Msg("Unsupported backend, expected grpc or rest")
+		return nil
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	querytypes "github.com/cosmos/cosmos-sdk/types/query"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	gogoproto "github.com/cosmos/gogoproto/proto"
+)
+
+// restServer answers every request with the status, the headers and the
+// body set, recording the last request.
+type restServer struct {
+	status  int
+	headers map[string]string
+	body    string
+
+	request *http.Request
+}
+
+func (s *restServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.request = r
+
+	for key, value := range s.headers {
+		w.Header().Set(key, value)
+	}
+
+	w.WriteHeader(s.status)
+	w.Write([]byte(s.body))
+}
+
+func newRESTServer(t *testing.T, server *restServer) *RESTConn {
+	t.Helper()
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	return NewRESTConn(httpServer.URL + "/")
+}
+
+func TestRESTConnRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		invoke   func(ctx context.Context, conn *RESTConn) error
+		path     string
+		rawQuery string
+	}{
+		{
+			name: "path variable",
+			invoke: func(ctx context.Context, conn *RESTConn) error {
+				_, err := stakingtypes.NewQueryClient(conn).Validator(ctx, &stakingtypes.QueryValidatorRequest{ValidatorAddr: "persistencevaloper1xxx"})
+				return err
+			},
+			path: "/cosmos/staking/v1beta1/validators/persistencevaloper1xxx",
+		},
+		{
+			name: "path variable with a slash",
+			invoke: func(ctx context.Context, conn *RESTConn) error {
+				_, err := banktypes.NewQueryClient(conn).DenomMetadata(ctx, &banktypes.QueryDenomMetadataRequest{Denom: "ibc/ABC"})
+				return err
+			},
+			path: "/cosmos/bank/v1beta1/denoms_metadata/ibc/ABC",
+		},
+		{
+			name: "query params",
+			invoke: func(ctx context.Context, conn *RESTConn) error {
+				_, err := banktypes.NewQueryClient(conn).Balance(ctx, &banktypes.QueryBalanceRequest{Address: "persistence1xxx", Denom: "uxprt"})
+				return err
+			},
+			path:     "/cosmos/bank/v1beta1/balances/persistence1xxx/by_denom",
+			rawQuery: "denom=uxprt",
+		},
+		{
+			name: "nested query params",
+			invoke: func(ctx context.Context, conn *RESTConn) error {
+				_, err := stakingtypes.NewQueryClient(conn).Validators(ctx, &stakingtypes.QueryValidatorsRequest{
+					Status:     stakingtypes.BondStatusBonded,
+					Pagination: &querytypes.PageRequest{Limit: 10},
+				})
+				return err
+			},
+			path:     "/cosmos/staking/v1beta1/validators",
+			rawQuery: "pagination.limit=10&status=BOND_STATUS_BONDED",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := &restServer{status: http.StatusOK, body: "{}"}
+			conn := newRESTServer(t, server)
+
+			if err := test.invoke(context.Background(), conn); err != nil {
+				t.Fatalf("could not query: %s", err)
+			}
+
+			if path := server.request.URL.EscapedPath(); path != test.path {
+				t.Errorf("got path %s, expected %s", path, test.path)
+			}
+
+			if rawQuery := server.request.URL.RawQuery; rawQuery != test.rawQuery {
+				t.Errorf("got query %s, expected %s", rawQuery, test.rawQuery)
+			}
+		})
+	}
+}
+
+func TestGetRequestFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  gogoproto.Message
+		expected map[string][]string
+	}{
+		{
+			name:     "empty request",
+			request:  &stakingtypes.QueryValidatorsRequest{},
+			expected: map[string][]string{},
+		},
+		{
+			name: "nested fields",
+			request: &stakingtypes.QueryValidatorsRequest{
+				Status:     stakingtypes.BondStatusBonded,
+				Pagination: &querytypes.PageRequest{Key: []byte("key"), Limit: 10, CountTotal: true},
+			},
+			expected: map[string][]string{
+				"status":                 {"BOND_STATUS_BONDED"},
+				"pagination.key":         {"a2V5"},
+				"pagination.limit":       {"10"},
+				"pagination.count_total": {"true"},
+			},
+		},
+		{
+			name:    "repeated field",
+			request: &txtypes.GetTxsEventRequest{Events: []string{"message.sender='a'", "tx.height>1"}, Page: 2},
+			expected: map[string][]string{
+				"events": {"message.sender='a'", "tx.height>1"},
+				"page":   {"2"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields, err := getRequestFields(test.request)
+			if err != nil {
+				t.Fatalf("could not get the fields: %s", err)
+			}
+
+			if !reflect.DeepEqual(fields, test.expected) {
+				t.Errorf("got fields %v, expected %v", fields, test.expected)
+			}
+		})
+	}
+}
+
+func TestRESTConnHeight(t *testing.T) {
+	server := &restServer{
+		status:  http.StatusOK,
+		headers: map[string]string{restHeaderPrefix + "X-Cosmos-Block-Height": "123"},
+		body:    `{"params": {"bond_denom": "uxprt"}}`,
+	}
+	conn := newRESTServer(t, server)
+
+	scrape := &Scrape{GrpcConn: conn, Height: 100}
+	if _, err := stakingtypes.NewQueryClient(conn).Params(scrape.Context(), &stakingtypes.QueryParamsRequest{}); err != nil {
+		t.Fatalf("could not query: %s", err)
+	}
+
+	if height := server.request.Header.Get("X-Cosmos-Block-Height"); height != "100" {
+		t.Errorf("got height header %q, expected 100", height)
+	}
+
+	height, err := getLatestHeight(context.Background(), conn)
+	if err != nil {
+		t.Fatalf("could not get the latest height: %s", err)
+	}
+
+	if height != 123 {
+		t.Errorf("got latest height %d, expected 123", height)
+	}
+}
+
+func TestGetRESTError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		code    codes.Code
+		message string
+	}{
+		{
+			name:    "gateway error",
+			status:  http.StatusNotFound,
+			body:    `{"code": 5, "message": "validator not found", "details": []}`,
+			code:    codes.NotFound,
+			message: "validator not found",
+		},
+		{
+			name:    "pruned height",
+			status:  http.StatusBadRequest,
+			body:    `{"code": 3, "message": "failed to load state at height 1"}`,
+			code:    codes.InvalidArgument,
+			message: "failed to load state at height 1",
+		},
+		{
+			name:    "proxy error page",
+			status:  http.StatusBadGateway,
+			body:    "<html>Bad Gateway</html>\n",
+			code:    codes.Unknown,
+			message: "/cosmos.staking.v1beta1.Query/Params: unexpected status 502: <html>Bad Gateway</html>",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := newRESTServer(t, &restServer{status: test.status, body: test.body})
+
+			_, err := stakingtypes.NewQueryClient(conn).Params(context.Background(), &stakingtypes.QueryParamsRequest{})
+			errorStatus, ok := status.FromError(err)
+			if !ok {
+				t.Fatalf("got error %v, expected a gRPC status", err)
+			}
+
+			if errorStatus.Code() != test.code || errorStatus.Message() != test.message {
+				t.Errorf("got %s %q, expected %s %q", errorStatus.Code(), errorStatus.Message(), test.code, test.message)
+			}
+		})
+	}
+}
+
+func TestNewRESTConnTimeout(t *testing.T) {
+	if timeout := NewRESTConn("http://localhost:1317").Client.Timeout; timeout != restTimeout {
+		t.Errorf("got timeout %s, expected %s", timeout, restTimeout)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	alertStatusFiring   = "firing"
+	alertStatusResolved = "resolved"
+
+	notifierTimeout    = 10 * time.Second
+	telegramDefaultURL = "https://api.telegram.org"
+)
+
+// AlertNotification is what is sent to the notifiers when an alert starts
+// firing or is resolved. It's also the data of the webhook body template.
+type AlertNotification struct {
+	Rule     string     `json:"rule"`
+	Type     string     `json:"type"`
+	Address  string     `json:"address"`
+	Status   string     `json:"status"`
+	Summary  string     `json:"summary"`
+	ChainID  string     `json:"chain_id"`
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// Text is the notification as a single line of text, for the chats.
+func (n AlertNotification) Text() string {
+	return fmt.Sprintf("[%s] %s on %s: %s", strings.ToUpper(n.Status), n.Rule, n.ChainID, n.Summary)
+}
+
+// AlertNotifier sends the alert notifications somewhere.
+type AlertNotifier interface {
+	Notify(ctx context.Context, notification AlertNotification) error
+}
+
+// WebhookNotifier posts the notification to any URL, either as JSON or with
+// the body rendered from the template.
+type WebhookNotifier struct {
+	URL      string
+	Template *template.Template
+	Client   *http.Client
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification AlertNotification) error {
+	var body []byte
+	if n.Template == nil {
+		encoded, err := json.Marshal(notification)
+		if err != nil {
+			return err
+		}
+
+		body = encoded
+	} else {
+		var buffer bytes.Buffer
+		if err := n.Template.Execute(&buffer, notification); err != nil {
+			return err
+		}
+
+		body = buffer.Bytes()
+	}
+
+	return postNotification(ctx, n.Client, n.URL, body)
+}
+
+// SlackNotifier posts the notification to a Slack incoming webhook, or any
+// other one accepting the same format, such as the Mattermost ones.
+type SlackNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, notification AlertNotification) error {
+	body, err := json.Marshal(map[string]string{"text": notification.Text()})
+	if err != nil {
+		return err
+	}
+
+	return postNotification(ctx, n.Client, n.URL, body)
+}
+
+// TelegramNotifier sends the notification to a Telegram chat through a bot.
+type TelegramNotifier struct {
+	URL    string
+	Token  string
+	ChatID string
+	Client *http.Client
+}
+
+func (n *TelegramNotifier) Notify(ctx context.Context, notification AlertNotification) error {
+	body, err := json.Marshal(map[string]string{
+		"chat_id": n.ChatID,
+		"text":    notification.Text(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return postNotification(ctx, n.Client, fmt.Sprintf("%s/bot%s/sendMessage", n.URL, n.Token), body)
+}
+
+func NewAlertNotifier(config AlertNotifierConfig) AlertNotifier {
+	client := &http.Client{Timeout: notifierTimeout}
+
+	switch config.Type {
+	case "slack":
+		return &SlackNotifier{URL: config.URL, Client: client}
+	case "telegram":
+		url := config.URL
+		if url == "" {
+			url = telegramDefaultURL
+		}
+
+		return &TelegramNotifier{
+			URL:    strings.TrimSuffix(url, "/"),
+			Token:  config.Token,
+			ChatID: config.ChatID,
+			Client: client,
+		}
+	default:
+		notifier := &WebhookNotifier{URL: config.URL, Client: client}
+		if config.Template != "" {
+			// Already validated along with the config.
+			notifier.Template = template.Must(template.New(config.Name).Parse(config.Template))
+		}
+
+		return notifier
+	}
+}
+
+func postNotification(ctx context.Context, client *http.Client, url string, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := client.Do(request)
+	if err != nil {
+		// The error has the URL in it, which has the Telegram bot token.
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", response.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+
+	return nil
+}
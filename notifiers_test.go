@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// notificationServer records the last notification posted to it, answering
+// with the status set.
+type notificationServer struct {
+	status int
+
+	path        string
+	contentType string
+	body        string
+}
+
+func (s *notificationServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.path = r.URL.Path
+	s.contentType = r.Header.Get("Content-Type")
+	s.body = string(body)
+
+	w.WriteHeader(s.status)
+	w.Write([]byte("bot token rejected\n"))
+}
+
+func TestNotifiers(t *testing.T) {
+	notification := AlertNotification{
+		Rule:     "validator-jailed",
+		Type:     alertTypeJailed,
+		Address:  "cosmosvaloper1xxx",
+		Status:   alertStatusFiring,
+		Summary:  "validator node is jailed",
+		ChainID:  "cosmoshub-4",
+		StartsAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name     string
+		notifier func(url string) AlertNotifier
+		path     string
+		body     string
+	}{
+		{
+			name: "slack",
+			notifier: func(url string) AlertNotifier {
+				return NewAlertNotifier(AlertNotifierConfig{Name: "slack", Type: "slack", URL: url + "/hooks/abc"})
+			},
+			path: "/hooks/abc",
+			body: `{"text":"[FIRING] validator-jailed on cosmoshub-4: validator node is jailed"}`,
+		},
+		{
+			name: "telegram",
+			notifier: func(url string) AlertNotifier {
+				return NewAlertNotifier(AlertNotifierConfig{Name: "telegram", Type: "telegram", URL: url + "/", Token: "123:secret", ChatID: "-100"})
+			},
+			path: "/bot123:secret/sendMessage",
+			body: `{"chat_id":"-100","text":"[FIRING] validator-jailed on cosmoshub-4: validator node is jailed"}`,
+		},
+		{
+			name: "webhook",
+			notifier: func(url string) AlertNotifier {
+				return NewAlertNotifier(AlertNotifierConfig{Name: "webhook", Type: "webhook", URL: url + "/alerts"})
+			},
+			path: "/alerts",
+			body: `{"rule":"validator-jailed","type":"jailed","address":"cosmosvaloper1xxx","status":"firing",` +
+				`"summary":"validator node is jailed","chain_id":"cosmoshub-4","starts_at":"2024-01-02T03:04:05Z"}`,
+		},
+		{
+			name: "webhook template",
+			notifier: func(url string) AlertNotifier {
+				return NewAlertNotifier(AlertNotifierConfig{
+					Name:     "webhook",
+					Type:     "webhook",
+					URL:      url + "/alerts",
+					Template: `{"alert": "{{ .Rule }}", "firing": {{ eq .Status "firing" }}}`,
+				})
+			},
+			path: "/alerts",
+			body: `{"alert": "validator-jailed", "firing": true}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := &notificationServer{status: http.StatusOK}
+			httpServer := httptest.NewServer(server)
+			t.Cleanup(httpServer.Close)
+
+			if err := test.notifier(httpServer.URL).Notify(context.Background(), notification); err != nil {
+				t.Fatalf("could not notify: %s", err)
+			}
+
+			if server.path != test.path {
+				t.Errorf("got path %s, expected %s", server.path, test.path)
+			}
+
+			if server.contentType != "application/json" {
+				t.Errorf("got content type %s, expected application/json", server.contentType)
+			}
+
+			if server.body != test.body {
+				t.Errorf("got body %s, expected %s", server.body, test.body)
+			}
+		})
+	}
+}
+
+func TestPostNotificationErrors(t *testing.T) {
+	const token = "123:secret"
+
+	server := &notificationServer{status: http.StatusUnauthorized}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	// A server that's gone, so the request itself fails.
+	closedServer := httptest.NewServer(http.NotFoundHandler())
+	closedServer.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		message string
+	}{
+		{name: "unexpected status", url: httpServer.URL, message: "unexpected status 401: bot token rejected"},
+		{name: "request failed", url: closedServer.URL, message: "connection refused"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notifier := NewAlertNotifier(AlertNotifierConfig{Name: "telegram", Type: "telegram", URL: test.url, Token: token, ChatID: "-100"})
+
+			err := notifier.Notify(context.Background(), AlertNotification{Status: alertStatusFiring})
+			if err == nil {
+				t.Fatalf("expected an error, got none")
+			}
+
+			if !strings.Contains(err.Error(), test.message) {
+				t.Errorf("got error %q, expected it to contain %q", err, test.message)
+			}
+
+			if strings.Contains(err.Error(), token) {
+				t.Errorf("got error %q with the bot token in it", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const ibcDenomPrefix = "ibc/"
+
+type operationalWalletBalance struct {
+	Balance   float64
+	SpendRate float64
+	UpdatedAt time.Time
+}
+
+// TimeToEmpty returns the seconds until the balance runs out at the spend
+// rate, if anything is being spent.
+func (b operationalWalletBalance) TimeToEmpty() (float64, bool) {
+	if b.SpendRate <= 0 {
+		return 0, false
+	}
+
+	return b.Balance / b.SpendRate, true
+}
+
+// OperationalWalletsMonitor keeps the balances of the operational wallets,
+// refreshed in the background so the spend rate can be observed between
+// the refreshes.
+type OperationalWalletsMonitor struct {
+	mutex sync.RWMutex
+
+	// balances are keyed by the wallet address and then by the denom.
+	balances map[string]map[string]operationalWalletBalance
+	height   int64
+}
+
+var operationalWalletsMonitor = &OperationalWalletsMonitor{
+	balances: map[string]map[string]operationalWalletBalance{},
+}
+
+func (m *OperationalWalletsMonitor) Refresh(grpcConn NodeConn) error {
+	scrape := NewScrape(grpcConn)
+	bankClient := banktypes.NewQueryClient(grpcConn)
+
+	for _, wallet := range OperationalWallets {
+		bankRes, err := bankClient.SpendableBalances(
+			scrape.Context(),
+			&banktypes.QuerySpendableBalancesRequest{Address: wallet.Address},
+		)
+		if err != nil {
+			log.Error().
+				Str("address", wallet.Address).
+				Err(err).
+				Msg("Could not get operational wallet balance")
+			continue
+		}
+
+		now := time.Now()
+		balances := make(map[string]float64, len(bankRes.Balances))
+		for _, balance := range bankRes.Balances {
+			value, _ := new(big.Float).SetInt(balance.Amount.BigInt()).Float64()
+			balances[balance.Denom] = getDisplayAmount(balance.Denom, value)
+		}
+
+		// A denom the wallet has spent entirely is not returned at all.
+		for denom := range wallet.Thresholds {
+			if !hasDenomFold(balances, denom) {
+				balances[m.getDenom(wallet.Address, denom)] = 0
+			}
+		}
+
+		m.update(wallet.Address, balances, now)
+	}
+
+	m.mutex.Lock()
+	m.height = scrape.Height
+	m.mutex.Unlock()
+
+	return nil
+}
+
+// getDenom returns the threshold denom as the node returns it. The config
+// keys are lowercased when read, so it's taken from the previous balances
+// of the wallet if it was there, or the IBC hash is uppercased back.
+func (m *OperationalWalletsMonitor) getDenom(address string, denom string) string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for previousDenom := range m.balances[address] {
+		if strings.EqualFold(previousDenom, denom) {
+			return previousDenom
+		}
+	}
+
+	if strings.HasPrefix(denom, ibcDenomPrefix) {
+		return ibcDenomPrefix + strings.ToUpper(strings.TrimPrefix(denom, ibcDenomPrefix))
+	}
+
+	return denom
+}
+
+func (m *OperationalWalletsMonitor) update(address string, balances map[string]float64, now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	previousBalances := m.balances[address]
+	updatedBalances := make(map[string]operationalWalletBalance, len(balances))
+
+	for denom, value := range balances {
+		current := operationalWalletBalance{Balance: value, UpdatedAt: now}
+
+		if previous, ok := previousBalances[denom]; ok {
+			elapsed := now.Sub(previous.UpdatedAt).Seconds()
+
+			switch {
+			case value < previous.Balance && elapsed > 0:
+				current.SpendRate = (previous.Balance - value) / elapsed
+			case value > previous.Balance:
+				// The wallet was topped up, so the spending in between is
+				// unknown, keeping the last observed rate.
+				current.SpendRate = previous.SpendRate
+			default:
+				current.SpendRate = 0
+			}
+		}
+
+		updatedBalances[denom] = current
+	}
+
+	m.balances[address] = updatedBalances
+}
+
+func OperationalWalletsHandler(w http.ResponseWriter, r *http.Request) {
+	requestStart := time.Now()
+
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	labels := []string{"address", "role", "owner", "denom"}
+
+	operationalWalletBalanceGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_operational_wallet_balance",
+			Help:        "Spendable balance of the operational wallet",
+			ConstLabels: ConstLabels,
+		},
+		labels,
+	)
+
+	operationalWalletThresholdGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_operational_wallet_threshold",
+			Help:        "Configured minimum balance of the operational wallet",
+			ConstLabels: ConstLabels,
+		},
+		labels,
+	)
+
+	operationalWalletHeadroomGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_operational_wallet_headroom",
+			Help:        "Balance of the operational wallet above its configured minimum",
+			ConstLabels: ConstLabels,
+		},
+		labels,
+	)
+
+	operationalWalletBelowThresholdGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_operational_wallet_below_threshold",
+			Help:        "1 if the operational wallet balance is below its configured minimum, 0 if not",
+			ConstLabels: ConstLabels,
+		},
+		labels,
+	)
+
+	operationalWalletSpendRateGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_operational_wallet_spend_rate",
+			Help:        "Observed spend rate of the operational wallet, per second",
+			ConstLabels: ConstLabels,
+		},
+		labels,
+	)
+
+	operationalWalletTimeToEmptyGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_operational_wallet_time_to_empty",
+			Help:        "Estimated time until the operational wallet runs out of funds at the observed spend rate, in seconds",
+			ConstLabels: ConstLabels,
+		},
+		labels,
+	)
+
+	snapshotHeightGauge := newSnapshotHeightGauge()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(operationalWalletBalanceGauge)
+	registry.MustRegister(operationalWalletThresholdGauge)
+	registry.MustRegister(operationalWalletHeadroomGauge)
+	registry.MustRegister(operationalWalletBelowThresholdGauge)
+	registry.MustRegister(operationalWalletSpendRateGauge)
+	registry.MustRegister(operationalWalletTimeToEmptyGauge)
+	registry.MustRegister(snapshotHeightGauge)
+
+	operationalWalletsMonitor.mutex.RLock()
+	for _, wallet := range OperationalWallets {
+		for denom, balance := range operationalWalletsMonitor.balances[wallet.Address] {
+			walletLabels := prometheus.Labels{
+				"address": wallet.Address,
+				"role":    wallet.Role,
+				"owner":   wallet.Owner,
+				"denom":   denom,
+			}
+
+			operationalWalletBalanceGauge.With(walletLabels).Set(balance.Balance)
+			operationalWalletSpendRateGauge.With(walletLabels).Set(balance.SpendRate)
+
+			if timeToEmpty, ok := balance.TimeToEmpty(); ok {
+				operationalWalletTimeToEmptyGauge.With(walletLabels).Set(timeToEmpty)
+			}
+
+			threshold, ok := getThreshold(wallet.Thresholds, denom)
+			if !ok {
+				continue
+			}
+
+			var belowThreshold float64
+			if balance.Balance < threshold {
+				belowThreshold = 1
+			} else {
+				belowThreshold = 0
+			}
+
+			operationalWalletThresholdGauge.With(walletLabels).Set(threshold)
+			operationalWalletHeadroomGauge.With(walletLabels).Set(balance.Balance - threshold)
+			operationalWalletBelowThresholdGauge.With(walletLabels).Set(belowThreshold)
+		}
+	}
+	setSnapshotHeight(snapshotHeightGauge, "operational-wallets", operationalWalletsMonitor.height)
+	operationalWalletsMonitor.mutex.RUnlock()
+
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+	sublogger.Info().
+		Str("method", "GET").
+		Str("endpoint", "/metrics/operational-wallets").
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
+
+// getThreshold returns the threshold for the denom. The config keys are
+// lowercased when read, so denoms are compared case-insensitively.
+func getThreshold(thresholds map[string]float64, denom string) (float64, bool) {
+	for thresholdDenom, threshold := range thresholds {
+		if strings.EqualFold(thresholdDenom, denom) {
+			return threshold, true
+		}
+	}
+
+	return 0, false
+}
+
+func hasDenomFold(balances map[string]float64, denom string) bool {
+	for balanceDenom := range balances {
+		if strings.EqualFold(balanceDenom, denom) {
+			return true
+		}
+	}
+
+	return false
+}
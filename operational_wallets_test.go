@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOperationalWalletsMonitorUpdate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		balances    []float64
+		spendRate   float64
+		timeToEmpty float64
+		spending    bool
+	}{
+		{
+			name:     "first observation",
+			balances: []float64{100},
+		},
+		{
+			name:        "spending",
+			balances:    []float64{100, 40},
+			spendRate:   1,
+			timeToEmpty: 40,
+			spending:    true,
+		},
+		{
+			name:        "topped up keeps the last rate",
+			balances:    []float64{100, 40, 1000},
+			spendRate:   1,
+			timeToEmpty: 1000,
+			spending:    true,
+		},
+		{
+			name:     "unchanged",
+			balances: []float64{100, 40, 40},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			monitor := &OperationalWalletsMonitor{balances: map[string]map[string]operationalWalletBalance{}}
+			for index, balance := range test.balances {
+				monitor.update("wallet", map[string]float64{"uxprt": balance}, start.Add(time.Duration(index)*time.Minute))
+			}
+
+			balance := monitor.balances["wallet"]["uxprt"]
+			if balance.SpendRate != test.spendRate {
+				t.Errorf("spend rate is %f, expected %f", balance.SpendRate, test.spendRate)
+			}
+
+			timeToEmpty, spending := balance.TimeToEmpty()
+			if spending != test.spending || timeToEmpty != test.timeToEmpty {
+				t.Errorf("time to empty is %f (%t), expected %f (%t)", timeToEmpty, spending, test.timeToEmpty, test.spending)
+			}
+		})
+	}
+}
+
+func TestOperationalWalletsMonitorGetDenom(t *testing.T) {
+	monitor := &OperationalWalletsMonitor{
+		balances: map[string]map[string]operationalWalletBalance{
+			"wallet": {"factory/persistence1abc/TOKEN": {}},
+		},
+	}
+
+	tests := []struct {
+		denom    string
+		expected string
+	}{
+		{denom: "factory/persistence1abc/token", expected: "factory/persistence1abc/TOKEN"},
+		{denom: "ibc/27394fb092d2eccd56123c74f36e4c1f926001ceada9ca97ea622b25f41e5eb2", expected: "ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2"},
+		{denom: "uxprt", expected: "uxprt"},
+	}
+
+	for _, test := range tests {
+		if denom := monitor.getDenom("wallet", test.denom); denom != test.expected {
+			t.Errorf("denom of %s is %s, expected %s", test.denom, denom, test.expected)
+		}
+	}
+}
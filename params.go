@@ -1,14 +1,11 @@
 package main
 
 import (
-	"context"
 	"net/http"
 	"strconv"
 	"sync"
 	"time"
 
-	"google.golang.org/grpc"
-
 	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
 	minttypes "github.com/cosmos/cosmos-sdk/x/mint/types"
 	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
@@ -18,7 +15,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func ParamsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.ClientConn) {
+func ParamsHandler(w http.ResponseWriter, r *http.Request, grpcConn NodeConn) {
 	requestStart := time.Now()
 
 	sublogger := log.With().
@@ -162,6 +159,9 @@ func ParamsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 	registry.MustRegister(paramsBonusProposerRewardGauge)
 	registry.MustRegister(paramsCommunityTaxGauge)
 
+	scrape := NewScrape(grpcConn)
+	ctx := scrape.Context()
+
 	var wg sync.WaitGroup
 
 	wg.Add(1)
@@ -172,7 +172,7 @@ func ParamsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 
 		stakingClient := stakingtypes.NewQueryClient(grpcConn)
 		paramsResponse, err := stakingClient.Params(
-			context.Background(),
+			ctx,
 			&stakingtypes.QueryParamsRequest{},
 		)
 		if err != nil {
@@ -193,12 +193,17 @@ func ParamsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if !capabilities.HasService(mintQueryService) {
+			sublogger.Trace().Msg("Mint module is not available, not querying mint params")
+			return
+		}
+
 		sublogger.Debug().Msg("Started querying global mint params")
 		queryStart := time.Now()
 
 		mintClient := minttypes.NewQueryClient(grpcConn)
 		paramsResponse, err := mintClient.Params(
-			context.Background(),
+			ctx,
 			&minttypes.QueryParamsRequest{},
 		)
 		if err != nil {
@@ -250,12 +255,17 @@ func ParamsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if !capabilities.HasService(slashingQueryService) {
+			sublogger.Trace().Msg("Slashing module is not available, not querying slashing params")
+			return
+		}
+
 		sublogger.Debug().Msg("Started querying global slashing params")
 		queryStart := time.Now()
 
 		slashingClient := slashingtypes.NewQueryClient(grpcConn)
 		paramsResponse, err := slashingClient.Params(
-			context.Background(),
+			ctx,
 			&slashingtypes.QueryParamsRequest{},
 		)
 		if err != nil {
@@ -300,12 +310,17 @@ func ParamsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if !capabilities.HasService(distributionQueryService) {
+			sublogger.Trace().Msg("Distribution module is not available, not querying distribution params")
+			return
+		}
+
 		sublogger.Debug().Msg("Started querying global distribution params")
 		queryStart := time.Now()
 
 		distributionClient := distributiontypes.NewQueryClient(grpcConn)
 		paramsResponse, err := distributionClient.Params(
-			context.Background(),
+			ctx,
 			&distributiontypes.QueryParamsRequest{},
 		)
 		if err != nil {
@@ -346,7 +361,7 @@ func ParamsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 
 	wg.Wait()
 
-	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	h := promhttp.HandlerFor(prometheus.Gatherers{registry, scrape.Gatherer()}, promhttp.HandlerOpts{})
 	h.ServeHTTP(w, r)
 	sublogger.Info().
 		Str("method", "GET").
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+// portfolioHoldings are the amounts held by a wallet or a whole portfolio,
+// keyed by the denom.
+type portfolioHoldings struct {
+	Balance   map[string]float64
+	Delegated map[string]float64
+	Unbonding map[string]float64
+	Rewards   map[string]float64
+	// DelegatedByValidator is keyed by the validator address and then by the denom.
+	DelegatedByValidator map[string]map[string]float64
+}
+
+func newPortfolioHoldings() *portfolioHoldings {
+	return &portfolioHoldings{
+		Balance:              map[string]float64{},
+		Delegated:            map[string]float64{},
+		Unbonding:            map[string]float64{},
+		Rewards:              map[string]float64{},
+		DelegatedByValidator: map[string]map[string]float64{},
+	}
+}
+
+func (h *portfolioHoldings) Add(other *portfolioHoldings) {
+	addAmounts(h.Balance, other.Balance)
+	addAmounts(h.Delegated, other.Delegated)
+	addAmounts(h.Unbonding, other.Unbonding)
+	addAmounts(h.Rewards, other.Rewards)
+
+	for validator, amounts := range other.DelegatedByValidator {
+		if _, ok := h.DelegatedByValidator[validator]; !ok {
+			h.DelegatedByValidator[validator] = map[string]float64{}
+		}
+		addAmounts(h.DelegatedByValidator[validator], amounts)
+	}
+}
+
+func addAmounts(to map[string]float64, from map[string]float64) {
+	for denom, amount := range from {
+		to[denom] += amount
+	}
+}
+
+// PortfolioHandler exports the totals of every portfolio from the config.
+// With ?detail=true the amounts of every wallet in them are exported too.
+func PortfolioHandler(w http.ResponseWriter, r *http.Request, grpcConn NodeConn) {
+	requestStart := time.Now()
+
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	detail := r.URL.Query().Get("detail") == "true"
+	scrape := NewScrape(grpcConn)
+
+	var gatherers prometheus.Gatherers
+	for _, portfolio := range Portfolios {
+		registry := prometheus.NewRegistry()
+		collectPortfolioMetrics(
+			prometheus.WrapRegistererWith(portfolio.Labels, registry),
+			scrape,
+			portfolio,
+			detail,
+			sublogger,
+		)
+		gatherers = append(gatherers, registry)
+	}
+	gatherers = append(gatherers, scrape.Gatherer())
+
+	h := promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
+		ErrorLog:      &sublogger,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+	h.ServeHTTP(w, r)
+	sublogger.Info().
+		Str("method", "GET").
+		Str("endpoint", "/metrics/portfolio").
+		Int("portfolios", len(Portfolios)).
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
+
+// collectPortfolioMetrics queries all the wallets of the portfolio, at most
+// MaxConcurrentTargets of them at a time, and registers the totals for it.
+// A wallet that could not be queried is left out of the totals and counted
+// in cosmos_portfolio_failed_wallets.
+func collectPortfolioMetrics(
+	registry prometheus.Registerer,
+	scrape *Scrape,
+	portfolio Portfolio,
+	detail bool,
+	sublogger zerolog.Logger,
+) {
+	portfolioWalletsGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_portfolio_wallets",
+			Help:        "Number of wallets in the portfolio",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"portfolio"},
+	)
+
+	portfolioFailedWalletsGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_portfolio_failed_wallets",
+			Help:        "Number of wallets in the portfolio that could not be queried and are not in the totals",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"portfolio"},
+	)
+
+	portfolioBalanceGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_portfolio_balance",
+			Help:        "Liquid balance of all the wallets in the portfolio",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"portfolio", "denom"},
+	)
+
+	portfolioDelegatedGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_portfolio_delegated",
+			Help:        "Delegated stake of all the wallets in the portfolio",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"portfolio", "denom"},
+	)
+
+	portfolioUnbondingGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_portfolio_unbonding",
+			Help:        "Unbonding stake of all the wallets in the portfolio",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"portfolio", "denom"},
+	)
+
+	portfolioRewardsGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_portfolio_rewards",
+			Help:        "Pending staking rewards of all the wallets in the portfolio",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"portfolio", "denom"},
+	)
+
+	portfolioDelegatedByValidatorGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_portfolio_delegated_by_validator",
+			Help:        "Delegated stake of all the wallets in the portfolio, per validator",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"portfolio", "denom", "validator_address"},
+	)
+
+	portfolioWalletBalanceGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_portfolio_wallet_balance",
+			Help:        "Liquid balance of a wallet in the portfolio",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"portfolio", "address", "denom"},
+	)
+
+	portfolioWalletDelegatedGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_portfolio_wallet_delegated",
+			Help:        "Delegated stake of a wallet in the portfolio",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"portfolio", "address", "denom"},
+	)
+
+	portfolioWalletUnbondingGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_portfolio_wallet_unbonding",
+			Help:        "Unbonding stake of a wallet in the portfolio",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"portfolio", "address", "denom"},
+	)
+
+	portfolioWalletRewardsGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_portfolio_wallet_rewards",
+			Help:        "Pending staking rewards of a wallet in the portfolio",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"portfolio", "address", "denom"},
+	)
+
+	registry.MustRegister(portfolioWalletsGauge)
+	registry.MustRegister(portfolioFailedWalletsGauge)
+	registry.MustRegister(portfolioBalanceGauge)
+	registry.MustRegister(portfolioDelegatedGauge)
+	registry.MustRegister(portfolioUnbondingGauge)
+	registry.MustRegister(portfolioRewardsGauge)
+	registry.MustRegister(portfolioDelegatedByValidatorGauge)
+
+	if detail {
+		registry.MustRegister(portfolioWalletBalanceGauge)
+		registry.MustRegister(portfolioWalletDelegatedGauge)
+		registry.MustRegister(portfolioWalletUnbondingGauge)
+		registry.MustRegister(portfolioWalletRewardsGauge)
+	}
+
+	totals := newPortfolioHoldings()
+	var failed int
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	semaphore := make(chan struct{}, MaxConcurrentTargets)
+
+	for _, address := range portfolio.Addresses {
+		wg.Add(1)
+		go func(address string) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			holdings, err := getWalletHoldings(scrape, address, sublogger)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err != nil {
+				failed++
+				return
+			}
+
+			totals.Add(holdings)
+
+			if !detail {
+				return
+			}
+
+			setWalletAmounts := func(gauge *prometheus.GaugeVec, amounts map[string]float64) {
+				for denom, amount := range amounts {
+					gauge.With(prometheus.Labels{
+						"portfolio": portfolio.Name,
+						"address":   address,
+						"denom":     denom,
+					}).Set(amount)
+				}
+			}
+
+			setWalletAmounts(portfolioWalletBalanceGauge, holdings.Balance)
+			setWalletAmounts(portfolioWalletDelegatedGauge, holdings.Delegated)
+			setWalletAmounts(portfolioWalletUnbondingGauge, holdings.Unbonding)
+			setWalletAmounts(portfolioWalletRewardsGauge, holdings.Rewards)
+		}(address)
+	}
+
+	wg.Wait()
+
+	portfolioWalletsGauge.With(prometheus.Labels{
+		"portfolio": portfolio.Name,
+	}).Set(float64(len(portfolio.Addresses)))
+
+	portfolioFailedWalletsGauge.With(prometheus.Labels{
+		"portfolio": portfolio.Name,
+	}).Set(float64(failed))
+
+	setAmounts := func(gauge *prometheus.GaugeVec, amounts map[string]float64) {
+		for denom, amount := range amounts {
+			gauge.With(prometheus.Labels{
+				"portfolio": portfolio.Name,
+				"denom":     denom,
+			}).Set(amount)
+		}
+	}
+
+	setAmounts(portfolioBalanceGauge, totals.Balance)
+	setAmounts(portfolioDelegatedGauge, totals.Delegated)
+	setAmounts(portfolioUnbondingGauge, totals.Unbonding)
+	setAmounts(portfolioRewardsGauge, totals.Rewards)
+
+	for validator, amounts := range totals.DelegatedByValidator {
+		for denom, amount := range amounts {
+			portfolioDelegatedByValidatorGauge.With(prometheus.Labels{
+				"portfolio":         portfolio.Name,
+				"denom":             denom,
+				"validator_address": validator,
+			}).Set(amount)
+		}
+	}
+}
+
+// getWalletHoldings queries the liquid balance, the delegations, the
+// unbonding delegations and the pending rewards of the wallet. It fails if
+// any of them could not be queried, so the portfolio totals are never
+// partial for a wallet.
+func getWalletHoldings(scrape *Scrape, address string, sublogger zerolog.Logger) (*portfolioHoldings, error) {
+	grpcConn := scrape.GrpcConn
+	ctx := scrape.Context()
+
+	resolvedAddress, err := resolveAddress(address, scrape.Validators)
+	if err != nil {
+		sublogger.Error().
+			Str("address", address).
+			Err(err).
+			Msg("Could not parse address")
+		return nil, err
+	}
+
+	myAddress := resolvedAddress.Account.String()
+	holdings := newPortfolioHoldings()
+
+	var errMutex sync.Mutex
+	var firstErr error
+	setErr := func(err error) {
+		errMutex.Lock()
+		defer errMutex.Unlock()
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sublogger.Debug().
+			Str("address", myAddress).
+			Msg("Started querying balance")
+		queryStart := time.Now()
+
+		balances, err := getAllBalances(ctx, grpcConn, myAddress)
+		if err != nil {
+			sublogger.Error().
+				Str("address", myAddress).
+				Err(err).
+				Msg("Could not get balance")
+			setErr(err)
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", myAddress).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying balance")
+
+		for _, balance := range balances {
+			holdings.Balance[balance.Denom] += getCoinValue(balance)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sublogger.Debug().
+			Str("address", myAddress).
+			Msg("Started querying delegations")
+		queryStart := time.Now()
+
+		delegations, err := getAllDelegatorDelegations(ctx, grpcConn, myAddress)
+		if err != nil {
+			sublogger.Error().
+				Str("address", myAddress).
+				Err(err).
+				Msg("Could not get delegations")
+			setErr(err)
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", myAddress).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying delegations")
+
+		for _, delegation := range delegations {
+			value := getCoinValue(delegation.Balance)
+			validator := delegation.Delegation.ValidatorAddress
+
+			holdings.Delegated[delegation.Balance.Denom] += value
+
+			if _, ok := holdings.DelegatedByValidator[validator]; !ok {
+				holdings.DelegatedByValidator[validator] = map[string]float64{}
+			}
+			holdings.DelegatedByValidator[validator][delegation.Balance.Denom] += value
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sublogger.Debug().
+			Str("address", myAddress).
+			Msg("Started querying unbonding delegations")
+		queryStart := time.Now()
+
+		unbondings, err := getAllDelegatorUnbondingDelegations(ctx, grpcConn, myAddress)
+		if err != nil {
+			sublogger.Error().
+				Str("address", myAddress).
+				Err(err).
+				Msg("Could not get unbonding delegations")
+			setErr(err)
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", myAddress).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying unbonding delegations")
+
+		for _, unbonding := range unbondings {
+			for _, entry := range unbonding.Entries {
+				// There's no denom in the response, it's always the bond
+				// denom, labelled as the balances and delegations are.
+				holdings.Unbonding[BondDenom] += getCoinValue(sdk.NewCoin(BondDenom, entry.Balance))
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sublogger.Debug().
+			Str("address", myAddress).
+			Msg("Started querying rewards")
+		queryStart := time.Now()
+
+		distributionClient := distributiontypes.NewQueryClient(grpcConn)
+		distributionRes, err := distributionClient.DelegationTotalRewards(
+			ctx,
+			&distributiontypes.QueryDelegationTotalRewardsRequest{DelegatorAddress: myAddress},
+		)
+		if err != nil {
+			sublogger.Error().
+				Str("address", myAddress).
+				Err(err).
+				Msg("Could not get rewards")
+			setErr(err)
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", myAddress).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying rewards")
+
+		for _, entry := range distributionRes.Total {
+			if value, err := strconv.ParseFloat(entry.Amount.String(), 64); err != nil {
+				sublogger.Error().
+					Str("address", myAddress).
+					Err(err).
+					Msg("Could not parse reward")
+				setErr(err)
+			} else {
+				holdings.Rewards[entry.Denom] += value / DenomCoefficient
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return holdings, nil
+}
+
+func getCoinValue(coin sdk.Coin) float64 {
+	value, _ := new(big.Float).SetInt(coin.Amount.BigInt()).Float64()
+	return value / DenomCoefficient
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	defaultPriceQuote = "usd"
+	priceTimeout      = 10 * time.Second
+)
+
+// valuedMetrics are the token amounts that get a _value_usd companion with
+// --price-value-metrics. The ones without a denom label are in the bond denom.
+var valuedMetrics = map[string]bool{
+	"cosmos_wallet_balance":               true,
+	"cosmos_wallet_spendable_balance":     true,
+	"cosmos_wallet_delegations":           true,
+	"cosmos_wallet_redelegations":         true,
+	"cosmos_wallet_unbondings":            true,
+	"cosmos_wallet_rewards":               true,
+	"cosmos_wallet_rewards_sum":           true,
+	"cosmos_wallet_vesting_locked":        true,
+	"cosmos_validator_tokens":             true,
+	"cosmos_validator_delegations":        true,
+	"cosmos_validator_commission":         true,
+	"cosmos_validator_rewards":            true,
+	"cosmos_validator_delegators_rewards": true,
+	"cosmos_validator_unbondings":         true,
+	"cosmos_general_bonded_tokens":        true,
+	"cosmos_general_not_bonded_tokens":    true,
+	"cosmos_general_community_pool":       true,
+	"cosmos_general_supply_total":         true,
+	"cosmos_general_circulating_supply":   true,
+}
+
+// PriceProvider gets the current price of a denom from somewhere.
+type PriceProvider interface {
+	// Price returns the price along with the time it was last updated at.
+	Price(ctx context.Context) (float64, time.Time, error)
+}
+
+// StaticPriceProvider returns the price set in the config, which is never
+// stale.
+type StaticPriceProvider struct {
+	Value float64
+}
+
+func (p *StaticPriceProvider) Price(ctx context.Context) (float64, time.Time, error) {
+	return p.Value, time.Now(), nil
+}
+
+// HTTPPriceProvider gets the price from the field of a JSON endpoint, such as
+// the simple price API of a price aggregator.
+type HTTPPriceProvider struct {
+	URL    string
+	Field  string
+	Client *http.Client
+}
+
+func (p *HTTPPriceProvider) Price(ctx context.Context) (float64, time.Time, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	response, err := p.Client.Do(request)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return 0, time.Time{}, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+
+	price, err := getJSONPrice(body, p.Field)
+	return price, time.Now(), err
+}
+
+// FilePriceProvider reads the price from a JSON file written by an external
+// job. The price is as old as the file.
+type FilePriceProvider struct {
+	Path  string
+	Field string
+}
+
+func (p *FilePriceProvider) Price(ctx context.Context) (float64, time.Time, error) {
+	info, err := os.Stat(p.Path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	body, err := os.ReadFile(p.Path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	price, err := getJSONPrice(body, p.Field)
+	return price, info.ModTime(), err
+}
+
+func NewPriceProvider(source PriceSource) PriceProvider {
+	switch source.Provider {
+	case "http":
+		return &HTTPPriceProvider{
+			URL:    source.URL,
+			Field:  source.Field,
+			Client: &http.Client{Timeout: priceTimeout},
+		}
+	case "file":
+		field := source.Field
+		if field == "" {
+			field = source.Denom
+		}
+
+		return &FilePriceProvider{Path: source.File, Field: field}
+	default:
+		return &StaticPriceProvider{Value: source.Price}
+	}
+}
+
+func getJSONPrice(body []byte, field string) (float64, error) {
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return 0, err
+	}
+
+	values := getJSONPath(document, field)
+	if len(values) == 0 {
+		return 0, fmt.Errorf("no %s field in the response", field)
+	}
+
+	price, err := getJSONFloat(values[0])
+	if err != nil {
+		return 0, err
+	}
+
+	if price <= 0 {
+		return 0, fmt.Errorf("price %f is not positive", price)
+	}
+
+	return price, nil
+}
+
+type priceKey struct {
+	Denom string
+	Quote string
+}
+
+type cachedPrice struct {
+	Price     float64
+	UpdatedAt time.Time
+	// Failed is set if the last refresh failed, in which case the price is
+	// the one from the refresh before.
+	Failed bool
+}
+
+type pricedSource struct {
+	Key      priceKey
+	Provider PriceProvider
+}
+
+// PriceCache keeps the prices from the providers, refreshed in the
+// background so the scrapes never wait for them.
+type PriceCache struct {
+	mutex sync.RWMutex
+
+	sources []pricedSource
+	prices  map[priceKey]*cachedPrice
+}
+
+var priceCache = NewPriceCache(nil)
+
+func NewPriceCache(sources []PriceSource) *PriceCache {
+	cache := &PriceCache{prices: map[priceKey]*cachedPrice{}}
+	for _, source := range sources {
+		cache.sources = append(cache.sources, pricedSource{
+			Key:      priceKey{Denom: getPriceDenom(source.Denom), Quote: source.Quote},
+			Provider: NewPriceProvider(source),
+		})
+	}
+
+	return cache
+}
+
+func (c *PriceCache) Refresh() error {
+	for _, source := range c.sources {
+		ctx, cancel := context.WithTimeout(context.Background(), priceTimeout)
+		price, updatedAt, err := source.Provider.Price(ctx)
+		cancel()
+
+		if err != nil {
+			log.Error().
+				Str("denom", source.Key.Denom).
+				Str("quote", source.Key.Quote).
+				Err(err).
+				Msg("Could not get price")
+		}
+
+		c.mutex.Lock()
+		cached, ok := c.prices[source.Key]
+		if !ok {
+			cached = &cachedPrice{}
+			c.prices[source.Key] = cached
+		}
+
+		if err != nil {
+			cached.Failed = true
+		} else {
+			cached.Price = price
+			cached.UpdatedAt = updatedAt
+			cached.Failed = false
+		}
+		c.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// Price returns the price of the denom, unless it's older than
+// --price-max-age.
+func (c *PriceCache) Price(denom, quote string) (float64, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	cached, ok := c.prices[priceKey{Denom: getPriceDenom(denom), Quote: quote}]
+	if !ok || cached.UpdatedAt.IsZero() || isPriceStale(cached) {
+		return 0, false
+	}
+
+	return cached.Price, true
+}
+
+// getPriceDenom returns the display denom for the bond denom, as the bond
+// denom amounts are exported in display units, so both share the same price.
+func getPriceDenom(denom string) string {
+	if denom == BondDenom {
+		return Denom
+	}
+
+	return denom
+}
+
+func isPriceStale(cached *cachedPrice) bool {
+	return time.Since(cached.UpdatedAt) > PriceMaxAge
+}
+
+// withValues adds the _value_usd companions of the valued metrics gathered,
+// if enabled with --price-value-metrics. The amounts without a price, or
+// with a stale one, get no companion.
+func withValues(gatherer prometheus.Gatherer) prometheus.Gatherer {
+	if !PriceValueMetrics {
+		return gatherer
+	}
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := gatherer.Gather()
+
+		var companions []*dto.MetricFamily
+		for _, family := range families {
+			if !valuedMetrics[family.GetName()] || family.GetType() != dto.MetricType_GAUGE {
+				continue
+			}
+
+			companion := &dto.MetricFamily{
+				Name: stringPointer(family.GetName() + "_value_usd"),
+				Help: stringPointer(family.GetHelp() + ", valued in USD"),
+				Type: family.Type,
+			}
+
+			for _, metric := range family.Metric {
+				denom := Denom
+				for _, label := range metric.Label {
+					if label.GetName() == "denom" {
+						denom = label.GetValue()
+					}
+				}
+
+				price, ok := priceCache.Price(denom, defaultPriceQuote)
+				if !ok {
+					continue
+				}
+
+				value := metric.GetGauge().GetValue() * price
+				companion.Metric = append(companion.Metric, &dto.Metric{
+					Label: metric.Label,
+					Gauge: &dto.Gauge{Value: &value},
+				})
+			}
+
+			if len(companion.Metric) > 0 {
+				companions = append(companions, companion)
+			}
+		}
+
+		families = append(families, companions...)
+		sort.Slice(families, func(i, j int) bool {
+			return families[i].GetName() < families[j].GetName()
+		})
+
+		return families, err
+	})
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func PricesHandler(w http.ResponseWriter, r *http.Request) {
+	requestStart := time.Now()
+
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	labels := []string{"denom", "quote"}
+
+	priceGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_price",
+			Help:        "Price of a display unit of the denom in the quote currency",
+			ConstLabels: ConstLabels,
+		},
+		labels,
+	)
+
+	priceUpdatedAtGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_price_updated_at",
+			Help:        "Unix timestamp of the last update of the price",
+			ConstLabels: ConstLabels,
+		},
+		labels,
+	)
+
+	priceStaleGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_price_stale",
+			Help:        "1 if the price is older than the max age and is not used to value the amounts, 0 if not",
+			ConstLabels: ConstLabels,
+		},
+		labels,
+	)
+
+	priceRefreshSuccessGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_price_refresh_success",
+			Help:        "1 if the last refresh of the price succeeded, 0 if not",
+			ConstLabels: ConstLabels,
+		},
+		labels,
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(priceGauge)
+	registry.MustRegister(priceUpdatedAtGauge)
+	registry.MustRegister(priceStaleGauge)
+	registry.MustRegister(priceRefreshSuccessGauge)
+
+	priceCache.mutex.RLock()
+	for key, cached := range priceCache.prices {
+		priceLabels := prometheus.Labels{
+			"denom": key.Denom,
+			"quote": key.Quote,
+		}
+
+		var refreshSuccess float64
+		if !cached.Failed {
+			refreshSuccess = 1
+		}
+		priceRefreshSuccessGauge.With(priceLabels).Set(refreshSuccess)
+
+		if cached.UpdatedAt.IsZero() {
+			continue
+		}
+
+		var stale float64
+		if isPriceStale(cached) {
+			stale = 1
+		}
+
+		priceGauge.With(priceLabels).Set(cached.Price)
+		priceUpdatedAtGauge.With(priceLabels).Set(float64(cached.UpdatedAt.Unix()))
+		priceStaleGauge.With(priceLabels).Set(stale)
+	}
+	priceCache.mutex.RUnlock()
+
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+	sublogger.Info().
+		Str("method", "GET").
+		Str("endpoint", "/metrics/prices").
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
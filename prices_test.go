@@ -0,0 +1,130 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestGetJSONPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		field    string
+		expected float64
+		err      bool
+	}{
+		{name: "number", body: `{"persistence": {"usd": 0.12}}`, field: "persistence.usd", expected: 0.12},
+		{name: "string", body: `{"uxprt": "0.5"}`, field: "uxprt", expected: 0.5},
+		{name: "first of a list", body: `{"prices": [{"usd": 2}, {"usd": 3}]}`, field: "prices.usd", expected: 2},
+		{name: "missing field", body: `{"persistence": {"eur": 0.1}}`, field: "persistence.usd", err: true},
+		{name: "zero", body: `{"usd": 0}`, field: "usd", err: true},
+		{name: "not a number", body: `{"usd": "unknown"}`, field: "usd", err: true},
+		{name: "invalid JSON", body: `{"usd":`, field: "usd", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			price, err := getJSONPrice([]byte(test.body), test.field)
+			if test.err {
+				if err == nil {
+					t.Errorf("expected an error, got price %f", price)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("could not get the price: %s", err)
+			}
+
+			if price != test.expected {
+				t.Errorf("got price %f, expected %f", price, test.expected)
+			}
+		})
+	}
+}
+
+func TestWithValues(t *testing.T) {
+	previousBondDenom, previousDenom := BondDenom, Denom
+	previousValueMetrics, previousMaxAge, previousCache := PriceValueMetrics, PriceMaxAge, priceCache
+	BondDenom, Denom = "uxprt", "xprt"
+	PriceValueMetrics, PriceMaxAge = true, time.Minute
+	t.Cleanup(func() {
+		BondDenom, Denom = previousBondDenom, previousDenom
+		PriceValueMetrics, PriceMaxAge, priceCache = previousValueMetrics, previousMaxAge, previousCache
+	})
+
+	priceCache = NewPriceCache([]PriceSource{
+		{Denom: "uxprt", Quote: defaultPriceQuote},
+		{Denom: "ibc/ABC", Quote: defaultPriceQuote},
+		{Denom: "ibc/STALE", Quote: defaultPriceQuote},
+	})
+	priceCache.prices[priceKey{Denom: "xprt", Quote: defaultPriceQuote}] = &cachedPrice{Price: 2, UpdatedAt: time.Now()}
+	priceCache.prices[priceKey{Denom: "ibc/ABC", Quote: defaultPriceQuote}] = &cachedPrice{Price: 0.5, UpdatedAt: time.Now()}
+	priceCache.prices[priceKey{Denom: "ibc/STALE", Quote: defaultPriceQuote}] = &cachedPrice{Price: 3, UpdatedAt: time.Now().Add(-time.Hour)}
+
+	balanceGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "cosmos_wallet_balance", Help: "Balance of the wallet"},
+		[]string{"address", "denom"},
+	)
+	balanceGauge.With(prometheus.Labels{"address": "wallet", "denom": "uxprt"}).Set(10)
+	balanceGauge.With(prometheus.Labels{"address": "wallet", "denom": "xprt"}).Set(20)
+	balanceGauge.With(prometheus.Labels{"address": "wallet", "denom": "ibc/ABC"}).Set(4)
+	balanceGauge.With(prometheus.Labels{"address": "wallet", "denom": "ibc/STALE"}).Set(5)
+	balanceGauge.With(prometheus.Labels{"address": "wallet", "denom": "ibc/UNKNOWN"}).Set(6)
+
+	tokensGauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "cosmos_general_bonded_tokens", Help: "Bonded tokens"})
+	tokensGauge.Set(100)
+
+	commissionRateGauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "cosmos_validator_commission_rate", Help: "Commission rate"})
+	commissionRateGauge.Set(0.05)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(balanceGauge, tokensGauge, commissionRateGauge)
+
+	families, err := withValues(registry).Gather()
+	if err != nil {
+		t.Fatalf("could not gather: %s", err)
+	}
+
+	values := map[string]map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			denom := ""
+			for _, label := range metric.Label {
+				if label.GetName() == "denom" {
+					denom = label.GetValue()
+				}
+			}
+
+			if _, ok := values[family.GetName()]; !ok {
+				values[family.GetName()] = map[string]float64{}
+			}
+
+			values[family.GetName()][denom] = metric.GetGauge().GetValue()
+		}
+	}
+
+	expected := map[string]map[string]float64{
+		"cosmos_wallet_balance_value_usd": {
+			"uxprt":   20,
+			"xprt":    40,
+			"ibc/ABC": 2,
+		},
+		"cosmos_general_bonded_tokens_value_usd": {
+			"": 200,
+		},
+	}
+
+	for name, expectedValues := range expected {
+		if !reflect.DeepEqual(values[name], expectedValues) {
+			t.Errorf("got %s %v, expected %v", name, values[name], expectedValues)
+		}
+	}
+
+	if _, ok := values["cosmos_validator_commission_rate_value_usd"]; ok {
+		t.Errorf("got a companion of cosmos_validator_commission_rate, expected none")
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/cmtservice"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	querytypes "github.com/cosmos/cosmos-sdk/types/query"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	govv1 "github.com/cosmos/cosmos-sdk/x/gov/types/v1"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// getAllValidators returns the whole validator set, following the pagination
+// until the last page.
+func getAllValidators(ctx context.Context, grpcConn NodeConn) ([]stakingtypes.Validator, error) {
+	stakingClient := stakingtypes.NewQueryClient(grpcConn)
+
+	var validators []stakingtypes.Validator
+	var nextKey []byte
+
+	for {
+		response, err := stakingClient.Validators(
+			ctx,
+			&stakingtypes.QueryValidatorsRequest{
+				Pagination: &querytypes.PageRequest{
+					Key:   nextKey,
+					Limit: Limit,
+				},
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		validators = append(validators, response.Validators...)
+
+		if response.Pagination == nil || len(response.Pagination.NextKey) == 0 {
+			return validators, nil
+		}
+		nextKey = response.Pagination.NextKey
+	}
+}
+
+// getAllValidatorUnbondingDelegations returns all unbonding delegations from
+// the validator, following the pagination until the last page.
+func getAllValidatorUnbondingDelegations(
+	ctx context.Context,
+	grpcConn NodeConn,
+	validatorAddress string,
+) ([]stakingtypes.UnbondingDelegation, error) {
+	stakingClient := stakingtypes.NewQueryClient(grpcConn)
+
+	var unbondings []stakingtypes.UnbondingDelegation
+	var nextKey []byte
+
+	for {
+		response, err := stakingClient.ValidatorUnbondingDelegations(
+			ctx,
+			&stakingtypes.QueryValidatorUnbondingDelegationsRequest{
+				ValidatorAddr: validatorAddress,
+				Pagination: &querytypes.PageRequest{
+					Key:   nextKey,
+					Limit: Limit,
+				},
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		unbondings = append(unbondings, response.UnbondingResponses...)
+
+		if response.Pagination == nil || len(response.Pagination.NextKey) == 0 {
+			return unbondings, nil
+		}
+		nextKey = response.Pagination.NextKey
+	}
+}
+
+// getAllAccounts returns every account on chain, following the pagination
+// until the last page. The accounts of the types the interface registry
+// doesn't know, such as the interchain accounts or the Ethermint ones, are
+// left out and counted by type URL in skipped.
+func getAllAccounts(ctx context.Context, grpcConn NodeConn, skipped map[string]int) ([]sdk.AccountI, error) {
+	authClient := authtypes.NewQueryClient(grpcConn)
+
+	var accounts []sdk.AccountI
+	var nextKey []byte
+
+	for {
+		response, err := authClient.Accounts(
+			ctx,
+			&authtypes.QueryAccountsRequest{
+				Pagination: &querytypes.PageRequest{
+					Key:   nextKey,
+					Limit: Limit,
+				},
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, unpackAccounts(response.Accounts, skipped)...)
+
+		if response.Pagination == nil || len(response.Pagination.NextKey) == 0 {
+			return accounts, nil
+		}
+		nextKey = response.Pagination.NextKey
+	}
+}
+
+// getModuleAccounts returns the module accounts, keyed by the module name.
+// The ones that can't be unpacked are counted in skipped, as for
+// getAllAccounts.
+func getModuleAccounts(ctx context.Context, grpcConn NodeConn, skipped map[string]int) (map[string]sdk.AccountI, error) {
+	authClient := authtypes.NewQueryClient(grpcConn)
+	response, err := authClient.ModuleAccounts(ctx, &authtypes.QueryModuleAccountsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make(map[string]sdk.AccountI, len(response.Accounts))
+	for _, account := range unpackAccounts(response.Accounts, skipped) {
+		moduleAccount, ok := account.(sdk.ModuleAccountI)
+		if !ok {
+			skipped[sdk.MsgTypeURL(account)]++
+			continue
+		}
+
+		accounts[moduleAccount.GetName()] = account
+	}
+
+	return accounts, nil
+}
+
+// unpackAccounts returns the accounts of the types the interface registry
+// knows, counting the others by type URL in skipped.
+func unpackAccounts(accountAnys []*codectypes.Any, skipped map[string]int) []sdk.AccountI {
+	accounts := make([]sdk.AccountI, 0, len(accountAnys))
+	for _, accountAny := range accountAnys {
+		var account sdk.AccountI
+		if err := interfaceRegistry.UnpackAny(accountAny, &account); err != nil {
+			skipped[accountAny.GetTypeUrl()]++
+			continue
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts
+}
+
+// getAllBalances returns all the balances of the account, following the
+// pagination until the last page.
+func getAllBalances(ctx context.Context, grpcConn NodeConn, address string) (sdk.Coins, error) {
+	bankClient := banktypes.NewQueryClient(grpcConn)
+
+	var balances sdk.Coins
+	var nextKey []byte
+
+	for {
+		response, err := bankClient.AllBalances(
+			ctx,
+			&banktypes.QueryAllBalancesRequest{
+				Address: address,
+				Pagination: &querytypes.PageRequest{
+					Key:   nextKey,
+					Limit: Limit,
+				},
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		balances = balances.Add(response.Balances...)
+
+		if response.Pagination == nil || len(response.Pagination.NextKey) == 0 {
+			return balances, nil
+		}
+		nextKey = response.Pagination.NextKey
+	}
+}
+
+// getAllDelegatorDelegations returns all the delegations of the delegator,
+// following the pagination until the last page.
+func getAllDelegatorDelegations(
+	ctx context.Context,
+	grpcConn NodeConn,
+	delegatorAddress string,
+) ([]stakingtypes.DelegationResponse, error) {
+	stakingClient := stakingtypes.NewQueryClient(grpcConn)
+
+	var delegations []stakingtypes.DelegationResponse
+	var nextKey []byte
+
+	for {
+		response, err := stakingClient.DelegatorDelegations(
+			ctx,
+			&stakingtypes.QueryDelegatorDelegationsRequest{
+				DelegatorAddr: delegatorAddress,
+				Pagination: &querytypes.PageRequest{
+					Key:   nextKey,
+					Limit: Limit,
+				},
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		delegations = append(delegations, response.DelegationResponses...)
+
+		if response.Pagination == nil || len(response.Pagination.NextKey) == 0 {
+			return delegations, nil
+		}
+		nextKey = response.Pagination.NextKey
+	}
+}
+
+// getAllDelegatorUnbondingDelegations returns all the unbonding delegations
+// of the delegator, following the pagination until the last page.
+func getAllDelegatorUnbondingDelegations(
+	ctx context.Context,
+	grpcConn NodeConn,
+	delegatorAddress string,
+) ([]stakingtypes.UnbondingDelegation, error) {
+	stakingClient := stakingtypes.NewQueryClient(grpcConn)
+
+	var unbondings []stakingtypes.UnbondingDelegation
+	var nextKey []byte
+
+	for {
+		response, err := stakingClient.DelegatorUnbondingDelegations(
+			ctx,
+			&stakingtypes.QueryDelegatorUnbondingDelegationsRequest{
+				DelegatorAddr: delegatorAddress,
+				Pagination: &querytypes.PageRequest{
+					Key:   nextKey,
+					Limit: Limit,
+				},
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		unbondings = append(unbondings, response.UnbondingResponses...)
+
+		if response.Pagination == nil || len(response.Pagination.NextKey) == 0 {
+			return unbondings, nil
+		}
+		nextKey = response.Pagination.NextKey
+	}
+}
+
+// getLatestHeight returns the height the node answers the queries at, taken
+// from the block height header of a cheap query.
+func getLatestHeight(ctx context.Context, grpcConn NodeConn) (int64, error) {
+	var header metadata.MD
+
+	stakingClient := stakingtypes.NewQueryClient(grpcConn)
+	if _, err := stakingClient.Params(ctx, &stakingtypes.QueryParamsRequest{}, grpc.Header(&header)); err != nil {
+		return 0, err
+	}
+
+	values := header.Get(grpctypes.GRPCBlockHeightHeader)
+	if len(values) == 0 {
+		return 0, fmt.Errorf("no %s header in the response", grpctypes.GRPCBlockHeightHeader)
+	}
+
+	return strconv.ParseInt(values[0], 10, 64)
+}
+
+// getBlockTime returns the time of the block at the height. Pruned nodes only
+// have the recent blocks, so the older ones require an archive node.
+func getBlockTime(ctx context.Context, grpcConn NodeConn, height int64) (time.Time, error) {
+	serviceClient := cmtservice.NewServiceClient(grpcConn)
+	response, err := serviceClient.GetBlockByHeight(ctx, &cmtservice.GetBlockByHeightRequest{Height: height})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if response.SdkBlock != nil {
+		return response.SdkBlock.Header.Time, nil
+	}
+
+	if response.Block != nil {
+		return response.Block.Header.Time, nil
+	}
+
+	return time.Time{}, fmt.Errorf("no block at height %d", height)
+}
+
+// getVotingProposals returns the proposals in the voting period, only the
+// ones the voter has voted on if it's set.
+func getVotingProposals(ctx context.Context, grpcConn NodeConn, voter string) ([]*govv1.Proposal, error) {
+	govClient := govv1.NewQueryClient(grpcConn)
+
+	var proposals []*govv1.Proposal
+	var nextKey []byte
+
+	for {
+		response, err := govClient.Proposals(
+			ctx,
+			&govv1.QueryProposalsRequest{
+				ProposalStatus: govv1.StatusVotingPeriod,
+				Voter:          voter,
+				Pagination: &querytypes.PageRequest{
+					Key:   nextKey,
+					Limit: Limit,
+				},
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		proposals = append(proposals, response.Proposals...)
+
+		if response.Pagination == nil || len(response.Pagination.NextKey) == 0 {
+			return proposals, nil
+		}
+		nextKey = response.Pagination.NextKey
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// listServices returns the names of all the services the node exposes.
+func listServices(ctx context.Context, grpcConn NodeConn) ([]string, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(grpcConn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+
+	response, err := sendReflectionRequest(stream, &reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	listResponse := response.GetListServicesResponse()
+	if listResponse == nil {
+		return nil, fmt.Errorf("unexpected server reflection response")
+	}
+
+	services := make([]string, 0, len(listResponse.Service))
+	for _, service := range listResponse.Service {
+		services = append(services, service.Name)
+	}
+
+	sort.Strings(services)
+
+	return services, nil
+}
+
+// getFileDescriptors returns the proto file defining the symbol along with
+// all of its dependencies, fetching the ones the node didn't send along with
+// it by their names.
+func getFileDescriptors(
+	ctx context.Context,
+	grpcConn NodeConn,
+	symbol string,
+) (*descriptorpb.FileDescriptorSet, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(grpcConn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+
+	files := map[string]*descriptorpb.FileDescriptorProto{}
+
+	addFiles := func(request *reflectionpb.ServerReflectionRequest) error {
+		response, err := sendReflectionRequest(stream, request)
+		if err != nil {
+			return err
+		}
+
+		fileResponse := response.GetFileDescriptorResponse()
+		if fileResponse == nil {
+			return fmt.Errorf("unexpected server reflection response")
+		}
+
+		for _, fileBytes := range fileResponse.FileDescriptorProto {
+			file := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(fileBytes, file); err != nil {
+				return err
+			}
+
+			files[file.GetName()] = file
+		}
+
+		return nil
+	}
+
+	if err := addFiles(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: symbol,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	for {
+		var missing []string
+		for _, file := range files {
+			for _, dependency := range file.GetDependency() {
+				if _, ok := files[dependency]; !ok {
+					missing = append(missing, dependency)
+				}
+			}
+		}
+
+		if len(missing) == 0 {
+			break
+		}
+
+		for _, dependency := range missing {
+			if _, ok := files[dependency]; ok {
+				continue
+			}
+
+			if err := addFiles(&reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{
+					FileByFilename: dependency,
+				},
+			}); err != nil {
+				return nil, fmt.Errorf("could not get %s: %w", dependency, err)
+			}
+
+			if _, ok := files[dependency]; !ok {
+				return nil, fmt.Errorf("node did not return %s", dependency)
+			}
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{File: make([]*descriptorpb.FileDescriptorProto, 0, len(files))}
+	for _, file := range files {
+		set.File = append(set.File, file)
+	}
+
+	return set, nil
+}
+
+func sendReflectionRequest(
+	stream reflectionpb.ServerReflection_ServerReflectionInfoClient,
+	request *reflectionpb.ServerReflectionRequest,
+) (*reflectionpb.ServerReflectionResponse, error) {
+	if err := stream.Send(request); err != nil {
+		return nil, err
+	}
+
+	response, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	if errorResponse := response.GetErrorResponse(); errorResponse != nil {
+		return nil, fmt.Errorf("server reflection error: %s", errorResponse.ErrorMessage)
+	}
+
+	return response, nil
+}
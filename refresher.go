@@ -0,0 +1,33 @@
+package main
+
+import (
+	"time"
+)
+
+// runPeriodically calls refresh right away and then once per interval, in the
+// background, for as long as the exporter is running.
+func runPeriodically(name string, interval time.Duration, refresh func() error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			log.Debug().Str("job", name).Msg("Started background refresh")
+			refreshStart := time.Now()
+
+			if err := refresh(); err != nil {
+				log.Error().
+					Str("job", name).
+					Err(err).
+					Msg("Background refresh failed")
+			} else {
+				log.Debug().
+					Str("job", name).
+					Float64("request-time", time.Since(refreshStart).Seconds()).
+					Msg("Finished background refresh")
+			}
+
+			<-ticker.C
+		}
+	}()
+}
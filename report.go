@@ -0,0 +1,727 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	gogoproto "github.com/cosmos/gogoproto/proto"
+	"github.com/spf13/cobra"
+)
+
+const (
+	reportDateLayout = "2006-01-02"
+	reportTxsPerPage = 100
+
+	ledgerKindRewards    = "rewards"
+	ledgerKindCommission = "commission"
+)
+
+var (
+	ReportFromDate   string
+	ReportToDate     string
+	ReportFromHeight int64
+	ReportToHeight   int64
+	ReportFormat     string
+	ReportOutput     string
+	ReportPrices     string
+	ReportValidators []string
+	ReportWallets    []string
+)
+
+// LedgerEntry is what an address earned of a single denom on a single day.
+// Earned is the change of the outstanding amount over the day plus what was
+// withdrawn during it, so withdrawals don't show up as negative earnings.
+// The bond denom is in the display denom, the others in their base units.
+type LedgerEntry struct {
+	Date      string   `json:"date"`
+	Address   string   `json:"address"`
+	Kind      string   `json:"kind"`
+	Denom     string   `json:"denom"`
+	Earned    float64  `json:"earned"`
+	Withdrawn float64  `json:"withdrawn"`
+	Price     *float64 `json:"price,omitempty"`
+	Value     *float64 `json:"value,omitempty"`
+}
+
+// reportPeriod covers the blocks after FromHeight up to ToHeight, all of
+// them committed on Date.
+type reportPeriod struct {
+	Date       string
+	FromHeight int64
+	ToHeight   int64
+}
+
+// reportAccount is a wallet or a validator the ledger is built for, along
+// with how to find its withdrawals in the indexed transactions.
+type reportAccount struct {
+	Kind    string
+	Address string
+	// TxQueries match the transactions withdrawing its rewards or
+	// commission. A transaction matching several of them is counted once.
+	TxQueries []string
+	// Event is the type of the events carrying the withdrawn amount, and
+	// EventAddress the delegator they must have, if any.
+	Event        string
+	EventAddress string
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Write a daily ledger of the rewards and commission earned",
+	Long: "Walk an archive node over a date or height range and write the rewards of the wallets " +
+		"and the commission of the validators earned every day, per denom, as CSV or JSON. " +
+		"The earnings are the changes of the outstanding amounts plus the withdrawals found in the indexed transactions.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		setLogger()
+
+		if err := loadConfigSections(); err != nil {
+			log.Fatal().Err(err).Msg("Could not parse config")
+		}
+
+		setBechConfig()
+
+		if ReportFormat != "csv" && ReportFormat != "json" {
+			log.Fatal().Str("format", ReportFormat).Msg("Unsupported format, expected csv or json")
+		}
+
+		var prices map[string]map[string]float64
+		if ReportPrices != "" {
+			var err error
+			if prices, err = loadPrices(ReportPrices); err != nil {
+				log.Fatal().Err(err).Msg("Could not read prices")
+			}
+		}
+
+		grpcConn := dialNode()
+		defer grpcConn.Close()
+
+		setDenom(grpcConn)
+
+		accounts := getReportAccounts(NewScrape(grpcConn))
+		if len(accounts) == 0 {
+			log.Fatal().Msg("No validators or wallets to report on, set them in the config or with --validator and --wallet")
+		}
+
+		ctx := context.Background()
+
+		fromHeight, toHeight, err := getReportRange(ctx, grpcConn)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Could not find the heights of the range")
+		}
+
+		periods, err := getReportPeriods(ctx, grpcConn, fromHeight, toHeight)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Could not split the range into days")
+		}
+
+		log.Info().
+			Int64("from-height", fromHeight).
+			Int64("to-height", toHeight).
+			Int("days", len(periods)).
+			Msg("Building the ledger")
+
+		var entries []LedgerEntry
+		for _, account := range accounts {
+			accountEntries, err := getLedgerEntries(ctx, grpcConn, account, periods)
+			if err != nil {
+				log.Error().
+					Str("address", account.Address).
+					Str("kind", account.Kind).
+					Err(err).
+					Msg("Could not build the ledger, skipping the address")
+				continue
+			}
+
+			entries = append(entries, accountEntries...)
+		}
+
+		for index := range entries {
+			price, ok := prices[entries[index].Date][entries[index].Denom]
+			if !ok {
+				continue
+			}
+
+			value := entries[index].Earned * price
+			entries[index].Price = &price
+			entries[index].Value = &value
+		}
+
+		file, err := os.Create(ReportOutput)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Could not create the output file")
+		}
+		defer file.Close()
+
+		if ReportFormat == "json" {
+			err = writeLedgerJSON(file, entries)
+		} else {
+			err = writeLedgerCSV(file, entries)
+		}
+
+		if err != nil {
+			log.Fatal().Err(err).Msg("Could not write the ledger")
+		}
+
+		log.Info().
+			Str("output", ReportOutput).
+			Int("entries", len(entries)).
+			Msg("Report finished")
+	},
+}
+
+func getReportAccounts(scrape *Scrape) []reportAccount {
+	var accounts []reportAccount
+
+	for _, target := range append(getTargets(ReportWallets), WalletTargets...) {
+		resolvedAddress, err := resolveAddress(target.Address, scrape.Validators)
+		if err != nil {
+			log.Error().
+				Str("address", target.Address).
+				Err(err).
+				Msg("Could not parse address")
+			continue
+		}
+
+		address := resolvedAddress.Account.String()
+		accounts = append(accounts, reportAccount{
+			Kind:         ledgerKindRewards,
+			Address:      address,
+			TxQueries:    []string{fmt.Sprintf("%s.%s='%s'", distributiontypes.EventTypeWithdrawRewards, distributiontypes.AttributeKeyDelegator, address)},
+			Event:        distributiontypes.EventTypeWithdrawRewards,
+			EventAddress: address,
+		})
+	}
+
+	for _, target := range append(getTargets(ReportValidators), ValidatorTargets...) {
+		resolvedAddress, err := resolveAddress(target.Address, scrape.Validators)
+		if err != nil {
+			log.Error().
+				Str("address", target.Address).
+				Err(err).
+				Msg("Could not parse validator address")
+			continue
+		}
+
+		// The commission withdrawal event has no validator attribute, so
+		// the transactions are matched by the operator account sending
+		// them, or by being executed through authz by a grantee, and the
+		// events are told apart by the messages they come from.
+		accounts = append(accounts, reportAccount{
+			Kind:    ledgerKindCommission,
+			Address: resolvedAddress.Validator.String(),
+			TxQueries: []string{
+				fmt.Sprintf(
+					"%s.%s EXISTS AND message.sender='%s'",
+					distributiontypes.EventTypeWithdrawCommission,
+					sdk.AttributeKeyAmount,
+					resolvedAddress.Account.String(),
+				),
+				fmt.Sprintf(
+					"%s.%s EXISTS AND message.action='%s'",
+					distributiontypes.EventTypeWithdrawCommission,
+					sdk.AttributeKeyAmount,
+					sdk.MsgTypeURL(&authz.MsgExec{}),
+				),
+			},
+			Event: distributiontypes.EventTypeWithdrawCommission,
+		})
+	}
+
+	return accounts
+}
+
+// getReportRange returns the height the starting state is taken at and the
+// last height of the range, from either the dates or the heights.
+func getReportRange(ctx context.Context, grpcConn NodeConn) (int64, int64, error) {
+	if ReportFromDate == "" {
+		toHeight := ReportToHeight
+		if toHeight == 0 {
+			latestHeight, err := getLatestHeight(ctx, grpcConn)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			toHeight = latestHeight
+		}
+
+		if ReportFromHeight <= 0 || ReportFromHeight >= toHeight {
+			return 0, 0, fmt.Errorf("invalid height range %d-%d", ReportFromHeight, toHeight)
+		}
+
+		return ReportFromHeight, toHeight, nil
+	}
+
+	fromDate, err := time.Parse(reportDateLayout, ReportFromDate)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	toDate := fromDate
+	if ReportToDate != "" {
+		if toDate, err = time.Parse(reportDateLayout, ReportToDate); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	earliestHeight, err := getEarliestHeight()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	latestHeight, err := getLatestHeight(ctx, grpcConn)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fromHeight, err := getHeightAt(ctx, grpcConn, fromDate, earliestHeight, latestHeight)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	toHeight, err := getHeightAt(ctx, grpcConn, toDate.AddDate(0, 0, 1), earliestHeight, latestHeight)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// The starting state is the one right before the first block of the
+	// range, unless the node doesn't have it.
+	fromHeight = max(fromHeight-1, earliestHeight)
+	toHeight--
+
+	if fromHeight >= toHeight {
+		return 0, 0, fmt.Errorf("no blocks between %s and %s", ReportFromDate, toDate.Format(reportDateLayout))
+	}
+
+	return fromHeight, toHeight, nil
+}
+
+// getReportPeriods splits the blocks after fromHeight up to toHeight into
+// the UTC days they were committed on. Days without blocks are left out.
+func getReportPeriods(ctx context.Context, grpcConn NodeConn, fromHeight, toHeight int64) ([]reportPeriod, error) {
+	var periods []reportPeriod
+
+	for height := fromHeight; height < toHeight; {
+		blockTime, err := getBlockTime(ctx, grpcConn, height+1)
+		if err != nil {
+			return nil, err
+		}
+
+		day := blockTime.UTC().Truncate(24 * time.Hour)
+
+		nextDayHeight, err := getHeightAt(ctx, grpcConn, day.AddDate(0, 0, 1), height+1, toHeight)
+		if err != nil {
+			return nil, err
+		}
+
+		periods = append(periods, reportPeriod{
+			Date:       day.Format(reportDateLayout),
+			FromHeight: height,
+			ToHeight:   nextDayHeight - 1,
+		})
+
+		height = nextDayHeight - 1
+	}
+
+	return periods, nil
+}
+
+// getHeightAt returns the first height between low and high with a block
+// committed at or after the time, or high+1 if there is none.
+func getHeightAt(ctx context.Context, grpcConn NodeConn, at time.Time, low, high int64) (int64, error) {
+	high++
+
+	for low < high {
+		middle := low + (high-low)/2
+
+		blockTime, err := getBlockTime(ctx, grpcConn, middle)
+		if err != nil {
+			return 0, err
+		}
+
+		if blockTime.Before(at) {
+			low = middle + 1
+		} else {
+			high = middle
+		}
+	}
+
+	return low, nil
+}
+
+func getLedgerEntries(
+	ctx context.Context,
+	grpcConn NodeConn,
+	account reportAccount,
+	periods []reportPeriod,
+) ([]LedgerEntry, error) {
+	withdrawn, err := getWithdrawals(ctx, grpcConn, account, periods)
+	if err != nil {
+		return nil, err
+	}
+
+	outstanding, err := getOutstanding(grpcConn, account, periods[0].FromHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []LedgerEntry
+	for index, period := range periods {
+		nextOutstanding, err := getOutstanding(grpcConn, account, period.ToHeight)
+		if err != nil {
+			return nil, err
+		}
+
+		denoms := map[string]bool{}
+		for _, amounts := range []map[string]float64{outstanding, nextOutstanding, withdrawn[index]} {
+			for denom := range amounts {
+				denoms[denom] = true
+			}
+		}
+
+		for denom := range denoms {
+			entries = append(entries, LedgerEntry{
+				Date:      period.Date,
+				Address:   account.Address,
+				Kind:      account.Kind,
+				Denom:     denom,
+				Earned:    nextOutstanding[denom] - outstanding[denom] + withdrawn[index][denom],
+				Withdrawn: withdrawn[index][denom],
+			})
+		}
+
+		outstanding = nextOutstanding
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+
+		return entries[i].Denom < entries[j].Denom
+	})
+
+	return entries, nil
+}
+
+// getOutstanding returns the pending rewards of the wallet or the
+// outstanding commission of the validator at the height.
+func getOutstanding(grpcConn NodeConn, account reportAccount, height int64) (map[string]float64, error) {
+	scrape := &Scrape{GrpcConn: grpcConn, Height: height}
+	distributionClient := distributiontypes.NewQueryClient(grpcConn)
+
+	var coins sdk.DecCoins
+	if account.Kind == ledgerKindCommission {
+		response, err := distributionClient.ValidatorCommission(
+			scrape.Context(),
+			&distributiontypes.QueryValidatorCommissionRequest{ValidatorAddress: account.Address},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		coins = response.Commission.Commission
+	} else {
+		response, err := distributionClient.DelegationTotalRewards(
+			scrape.Context(),
+			&distributiontypes.QueryDelegationTotalRewardsRequest{DelegatorAddress: account.Address},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		coins = response.Total
+	}
+
+	amounts := make(map[string]float64, len(coins))
+	for _, coin := range coins {
+		value, err := strconv.ParseFloat(coin.Amount.String(), 64)
+		if err != nil {
+			return nil, err
+		}
+
+		denom, amount := getLedgerAmount(coin.Denom, value)
+		amounts[denom] = amount
+	}
+
+	return amounts, nil
+}
+
+// getLedgerAmount converts an amount of the bond denom to the display denom.
+// The coefficient is only known for the bond denom, so the others are left
+// in their base units.
+func getLedgerAmount(denom string, amount float64) (string, float64) {
+	if denom != BondDenom {
+		return denom, amount
+	}
+
+	return Denom, getDisplayAmount(denom, amount)
+}
+
+// getWithdrawals returns the amounts withdrawn during every period, from the
+// events of the indexed transactions.
+func getWithdrawals(
+	ctx context.Context,
+	grpcConn NodeConn,
+	account reportAccount,
+	periods []reportPeriod,
+) ([]map[string]float64, error) {
+	withdrawn := make([]map[string]float64, len(periods))
+	for index := range withdrawn {
+		withdrawn[index] = map[string]float64{}
+	}
+
+	serviceClient := txtypes.NewServiceClient(grpcConn)
+	counted := map[string]bool{}
+
+	for _, txQuery := range account.TxQueries {
+		query := fmt.Sprintf(
+			"%s AND tx.height>%d AND tx.height<=%d",
+			txQuery,
+			periods[0].FromHeight,
+			periods[len(periods)-1].ToHeight,
+		)
+
+		for page := uint64(1); ; page++ {
+			response, err := serviceClient.GetTxsEvent(ctx, &txtypes.GetTxsEventRequest{
+				Query:   query,
+				Page:    page,
+				Limit:   reportTxsPerPage,
+				OrderBy: txtypes.OrderBy_ORDER_BY_ASC,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, txResponse := range response.TxResponses {
+				if txResponse.Code != 0 || counted[txResponse.TxHash] {
+					continue
+				}
+				counted[txResponse.TxHash] = true
+
+				index := sort.Search(len(periods), func(i int) bool {
+					return periods[i].ToHeight >= txResponse.Height
+				})
+				if index == len(periods) {
+					continue
+				}
+
+				if err := addWithdrawals(withdrawn[index], account, txResponse); err != nil {
+					return nil, fmt.Errorf("could not get the amount withdrawn in %s: %w", txResponse.TxHash, err)
+				}
+			}
+
+			if len(response.TxResponses) < reportTxsPerPage || page*reportTxsPerPage >= response.Total {
+				break
+			}
+		}
+	}
+
+	return withdrawn, nil
+}
+
+// addWithdrawals adds the amounts the transaction withdrew for the account.
+// The commission withdrawal events don't tell the validator, so they're
+// matched in order with the messages withdrawing the commission.
+func addWithdrawals(withdrawn map[string]float64, account reportAccount, txResponse *sdk.TxResponse) error {
+	var commissionValidators []string
+	if account.Kind == ledgerKindCommission {
+		var err error
+		if commissionValidators, err = getCommissionValidators(txResponse.Tx); err != nil {
+			return err
+		}
+	}
+
+	commissionIndex := 0
+	for _, event := range txResponse.Events {
+		if event.Type != account.Event {
+			continue
+		}
+
+		var amount, delegator string
+		for _, attribute := range event.Attributes {
+			switch attribute.Key {
+			case sdk.AttributeKeyAmount:
+				amount = attribute.Value
+			case distributiontypes.AttributeKeyDelegator:
+				delegator = attribute.Value
+			}
+		}
+
+		if account.EventAddress != "" && delegator != account.EventAddress {
+			continue
+		}
+
+		if account.Kind == ledgerKindCommission {
+			if commissionIndex >= len(commissionValidators) {
+				return fmt.Errorf("more commission withdrawals than messages withdrawing it")
+			}
+
+			validator := commissionValidators[commissionIndex]
+			commissionIndex++
+
+			if validator != account.Address {
+				continue
+			}
+		}
+
+		coins, err := sdk.ParseCoinsNormalized(amount)
+		if err != nil {
+			return err
+		}
+
+		for _, coin := range coins {
+			value, _ := new(big.Float).SetInt(coin.Amount.BigInt()).Float64()
+			denom, amount := getLedgerAmount(coin.Denom, value)
+			withdrawn[denom] += amount
+		}
+	}
+
+	return nil
+}
+
+// getCommissionValidators returns the validators the messages of the
+// transaction withdraw the commission of, in the order they're executed,
+// including the messages executed through authz.
+func getCommissionValidators(txAny *codectypes.Any) ([]string, error) {
+	if txAny == nil {
+		return nil, fmt.Errorf("no transaction in the response")
+	}
+
+	var tx txtypes.Tx
+	if err := gogoproto.Unmarshal(txAny.Value, &tx); err != nil {
+		return nil, err
+	}
+
+	if tx.Body == nil {
+		return nil, nil
+	}
+
+	return getMsgsCommissionValidators(tx.Body.Messages)
+}
+
+func getMsgsCommissionValidators(msgs []*codectypes.Any) ([]string, error) {
+	var validators []string
+
+	for _, msg := range msgs {
+		switch msg.TypeUrl {
+		case sdk.MsgTypeURL(&distributiontypes.MsgWithdrawValidatorCommission{}):
+			var withdrawMsg distributiontypes.MsgWithdrawValidatorCommission
+			if err := gogoproto.Unmarshal(msg.Value, &withdrawMsg); err != nil {
+				return nil, err
+			}
+
+			validators = append(validators, withdrawMsg.ValidatorAddress)
+		case sdk.MsgTypeURL(&authz.MsgExec{}):
+			var execMsg authz.MsgExec
+			if err := gogoproto.Unmarshal(msg.Value, &execMsg); err != nil {
+				return nil, err
+			}
+
+			execValidators, err := getMsgsCommissionValidators(execMsg.Msgs)
+			if err != nil {
+				return nil, err
+			}
+
+			validators = append(validators, execValidators...)
+		}
+	}
+
+	return validators, nil
+}
+
+// loadPrices reads the prices from a CSV file with the date, the denom and
+// the price on every line, for example 2024-01-31,atom,9.87. The prices are
+// per unit of the denom as it's written in the ledger.
+func loadPrices(path string) (map[string]map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 3
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	prices := map[string]map[string]float64{}
+	for index, record := range records {
+		price, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			// The header, if there is one.
+			if index == 0 {
+				continue
+			}
+
+			return nil, fmt.Errorf("line %d: %w", index+1, err)
+		}
+
+		if _, ok := prices[record[0]]; !ok {
+			prices[record[0]] = map[string]float64{}
+		}
+
+		prices[record[0]][record[1]] = price
+	}
+
+	return prices, nil
+}
+
+func writeLedgerCSV(w io.Writer, entries []LedgerEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"date", "address", "kind", "denom", "earned", "withdrawn", "price", "value"}); err != nil {
+		return err
+	}
+
+	formatOptional := func(value *float64) string {
+		if value == nil {
+			return ""
+		}
+
+		return strconv.FormatFloat(*value, 'f', -1, 64)
+	}
+
+	for _, entry := range entries {
+		if err := writer.Write([]string{
+			entry.Date,
+			entry.Address,
+			entry.Kind,
+			entry.Denom,
+			strconv.FormatFloat(entry.Earned, 'f', -1, 64),
+			strconv.FormatFloat(entry.Withdrawn, 'f', -1, 64),
+			formatOptional(entry.Price),
+			formatOptional(entry.Value),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeLedgerJSON(w io.Writer, entries []LedgerEntry) error {
+	if entries == nil {
+		entries = []LedgerEntry{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	sdkmath "cosmossdk.io/math"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cosmos/cosmos-sdk/client/grpc/cmtservice"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+)
+
+// reportConn answers the queries of the report from the block times, the
+// outstanding amounts per height and the withdrawal transactions it holds.
+type reportConn struct {
+	blockTimes  map[int64]time.Time
+	outstanding map[int64]sdk.DecCoins
+	txs         []*sdk.TxResponse
+}
+
+func (c *reportConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	var height int64
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get(grpctypes.GRPCBlockHeightHeader); len(values) > 0 {
+			height, _ = strconv.ParseInt(values[0], 10, 64)
+		}
+	}
+
+	switch reply := reply.(type) {
+	case *cmtservice.GetBlockByHeightResponse:
+		request := args.(*cmtservice.GetBlockByHeightRequest)
+		blockTime, ok := c.blockTimes[request.Height]
+		if !ok {
+			return fmt.Errorf("no block at height %d", request.Height)
+		}
+
+		reply.SdkBlock = &cmtservice.Block{Header: cmtservice.Header{Time: blockTime}}
+	case *distributiontypes.QueryDelegationTotalRewardsResponse:
+		reply.Total = c.outstanding[height]
+	case *distributiontypes.QueryValidatorCommissionResponse:
+		reply.Commission = distributiontypes.ValidatorAccumulatedCommission{Commission: c.outstanding[height]}
+	case *txtypes.GetTxsEventResponse:
+		reply.TxResponses = c.txs
+		reply.Total = uint64(len(c.txs))
+	default:
+		return fmt.Errorf("unexpected query %s", method)
+	}
+
+	return nil
+}
+
+func (c *reportConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, fmt.Errorf("unexpected stream %s", method)
+}
+
+func (c *reportConn) Close() error {
+	return nil
+}
+
+// getBlockTimes returns the times of the blocks from height 1 on.
+func getBlockTimes(times ...string) map[int64]time.Time {
+	blockTimes := make(map[int64]time.Time, len(times))
+	for index, value := range times {
+		blockTime, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			panic(err)
+		}
+
+		blockTimes[int64(index+1)] = blockTime
+	}
+
+	return blockTimes
+}
+
+func TestGetHeightAt(t *testing.T) {
+	conn := &reportConn{blockTimes: getBlockTimes(
+		"2024-01-01T03:00:00Z",
+		"2024-01-01T09:00:00Z",
+		"2024-01-01T15:00:00Z",
+		"2024-01-01T21:00:00Z",
+		"2024-01-02T03:00:00Z",
+		"2024-01-02T09:00:00Z",
+	)}
+
+	tests := []struct {
+		name     string
+		at       string
+		low      int64
+		high     int64
+		expected int64
+	}{
+		{
+			name:     "between blocks",
+			at:       "2024-01-02T00:00:00Z",
+			low:      1,
+			high:     6,
+			expected: 5,
+		},
+		{
+			name:     "at a block",
+			at:       "2024-01-01T09:00:00Z",
+			low:      1,
+			high:     6,
+			expected: 2,
+		},
+		{
+			name:     "before the range",
+			at:       "2024-01-01T00:00:00Z",
+			low:      3,
+			high:     6,
+			expected: 3,
+		},
+		{
+			name:     "after the range",
+			at:       "2024-01-03T00:00:00Z",
+			low:      1,
+			high:     6,
+			expected: 7,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			at, _ := time.Parse(time.RFC3339, test.at)
+
+			height, err := getHeightAt(context.Background(), conn, at, test.low, test.high)
+			if err != nil {
+				t.Fatalf("could not get the height: %s", err)
+			}
+
+			if height != test.expected {
+				t.Errorf("got height %d, expected %d", height, test.expected)
+			}
+		})
+	}
+}
+
+func TestGetReportPeriods(t *testing.T) {
+	tests := []struct {
+		name       string
+		blockTimes map[int64]time.Time
+		fromHeight int64
+		toHeight   int64
+		expected   []reportPeriod
+	}{
+		{
+			name: "consecutive days",
+			blockTimes: getBlockTimes(
+				"2024-01-01T03:00:00Z",
+				"2024-01-01T09:00:00Z",
+				"2024-01-01T15:00:00Z",
+				"2024-01-01T21:00:00Z",
+				"2024-01-02T03:00:00Z",
+				"2024-01-02T09:00:00Z",
+			),
+			fromHeight: 1,
+			toHeight:   6,
+			expected: []reportPeriod{
+				{Date: "2024-01-01", FromHeight: 1, ToHeight: 4},
+				{Date: "2024-01-02", FromHeight: 4, ToHeight: 6},
+			},
+		},
+		{
+			name: "day without blocks",
+			blockTimes: getBlockTimes(
+				"2024-01-01T10:00:00Z",
+				"2024-01-01T20:00:00Z",
+				"2024-01-03T10:00:00Z",
+				"2024-01-03T11:00:00Z",
+			),
+			fromHeight: 1,
+			toHeight:   4,
+			expected: []reportPeriod{
+				{Date: "2024-01-01", FromHeight: 1, ToHeight: 2},
+				{Date: "2024-01-03", FromHeight: 2, ToHeight: 4},
+			},
+		},
+		{
+			name: "single day",
+			blockTimes: getBlockTimes(
+				"2024-01-01T10:00:00Z",
+				"2024-01-01T20:00:00Z",
+				"2024-01-01T21:00:00Z",
+			),
+			fromHeight: 1,
+			toHeight:   3,
+			expected: []reportPeriod{
+				{Date: "2024-01-01", FromHeight: 1, ToHeight: 3},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := &reportConn{blockTimes: test.blockTimes}
+
+			periods, err := getReportPeriods(context.Background(), conn, test.fromHeight, test.toHeight)
+			if err != nil {
+				t.Fatalf("could not get the periods: %s", err)
+			}
+
+			if !reflect.DeepEqual(periods, test.expected) {
+				t.Errorf("got periods %+v, expected %+v", periods, test.expected)
+			}
+		})
+	}
+}
+
+// getWithdrawalEvent returns an event of the type withdrawing the amount,
+// for the delegator if it's set.
+func getWithdrawalEvent(eventType, delegator, amount string) abci.Event {
+	attributes := []abci.EventAttribute{{Key: sdk.AttributeKeyAmount, Value: amount}}
+	if delegator != "" {
+		attributes = append(attributes, abci.EventAttribute{Key: distributiontypes.AttributeKeyDelegator, Value: delegator})
+	}
+
+	return abci.Event{Type: eventType, Attributes: attributes}
+}
+
+// getTx returns a transaction at the height with the result code, the
+// events and the messages.
+func getTx(t *testing.T, height int64, code uint32, events []abci.Event, msgs ...sdk.Msg) *sdk.TxResponse {
+	t.Helper()
+
+	msgAnys := make([]*codectypes.Any, 0, len(msgs))
+	for _, msg := range msgs {
+		msgAny, err := codectypes.NewAnyWithValue(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgAnys = append(msgAnys, msgAny)
+	}
+
+	txAny, err := codectypes.NewAnyWithValue(&txtypes.Tx{Body: &txtypes.TxBody{Messages: msgAnys}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &sdk.TxResponse{
+		Height: height,
+		Code:   code,
+		TxHash: fmt.Sprintf("TX%d", height),
+		Tx:     txAny,
+		Events: events,
+	}
+}
+
+func packMsg(t *testing.T, msg sdk.Msg) *codectypes.Any {
+	t.Helper()
+
+	msgAny, err := codectypes.NewAnyWithValue(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return msgAny
+}
+
+func TestGetLedgerEntries(t *testing.T) {
+	previousBondDenom, previousDenom, previousCoefficient := BondDenom, Denom, DenomCoefficient
+	BondDenom, Denom, DenomCoefficient = "uatom", "atom", 1000000
+	t.Cleanup(func() {
+		BondDenom, Denom, DenomCoefficient = previousBondDenom, previousDenom, previousCoefficient
+	})
+
+	outstanding := map[int64]sdk.DecCoins{
+		10: sdk.NewDecCoins(sdk.NewDecCoin("uatom", sdkmath.NewInt(1000000))),
+		20: sdk.NewDecCoins(
+			sdk.NewDecCoin("uatom", sdkmath.NewInt(3000000)),
+			sdk.NewDecCoin("ibc/ABC", sdkmath.NewInt(5)),
+		),
+		30: sdk.NewDecCoins(sdk.NewDecCoin("uatom", sdkmath.NewInt(500000))),
+	}
+
+	periods := []reportPeriod{
+		{Date: "2024-01-01", FromHeight: 10, ToHeight: 20},
+		{Date: "2024-01-02", FromHeight: 20, ToHeight: 30},
+	}
+
+	commissionAccount := reportAccount{
+		Kind:    ledgerKindCommission,
+		Address: "validator",
+		// Both queries match the same transactions here, which must be
+		// counted once.
+		TxQueries: []string{"sender", "authz"},
+		Event:     distributiontypes.EventTypeWithdrawCommission,
+	}
+
+	tests := []struct {
+		name     string
+		account  reportAccount
+		txs      []*sdk.TxResponse
+		expected []LedgerEntry
+	}{
+		{
+			name: "rewards",
+			account: reportAccount{
+				Kind:         ledgerKindRewards,
+				Address:      "wallet",
+				TxQueries:    []string{"delegator"},
+				Event:        distributiontypes.EventTypeWithdrawRewards,
+				EventAddress: "wallet",
+			},
+			txs: []*sdk.TxResponse{
+				getTx(t, 25, 0, []abci.Event{getWithdrawalEvent(distributiontypes.EventTypeWithdrawRewards, "wallet", "4000000uatom,5ibc/ABC")}),
+				getTx(t, 26, 0, []abci.Event{getWithdrawalEvent(distributiontypes.EventTypeWithdrawRewards, "other", "7000000uatom")}),
+				getTx(t, 27, 1, []abci.Event{getWithdrawalEvent(distributiontypes.EventTypeWithdrawRewards, "wallet", "7000000uatom")}),
+			},
+			expected: []LedgerEntry{
+				{Date: "2024-01-01", Address: "wallet", Kind: ledgerKindRewards, Denom: "atom", Earned: 2},
+				{Date: "2024-01-01", Address: "wallet", Kind: ledgerKindRewards, Denom: "ibc/ABC", Earned: 5},
+				{Date: "2024-01-02", Address: "wallet", Kind: ledgerKindRewards, Denom: "atom", Earned: 1.5, Withdrawn: 4},
+				{Date: "2024-01-02", Address: "wallet", Kind: ledgerKindRewards, Denom: "ibc/ABC", Earned: 0, Withdrawn: 5},
+			},
+		},
+		{
+			name:    "commission",
+			account: commissionAccount,
+			txs: []*sdk.TxResponse{
+				getTx(
+					t, 15, 0,
+					[]abci.Event{getWithdrawalEvent(distributiontypes.EventTypeWithdrawCommission, "", "2000000uatom")},
+					&distributiontypes.MsgWithdrawValidatorCommission{ValidatorAddress: "validator"},
+				),
+			},
+			expected: []LedgerEntry{
+				{Date: "2024-01-01", Address: "validator", Kind: ledgerKindCommission, Denom: "atom", Earned: 4, Withdrawn: 2},
+				{Date: "2024-01-01", Address: "validator", Kind: ledgerKindCommission, Denom: "ibc/ABC", Earned: 5},
+				{Date: "2024-01-02", Address: "validator", Kind: ledgerKindCommission, Denom: "atom", Earned: -2.5},
+				{Date: "2024-01-02", Address: "validator", Kind: ledgerKindCommission, Denom: "ibc/ABC", Earned: -5},
+			},
+		},
+		{
+			// Only the withdrawal of the validator's own commission
+			// through authz is counted, not the one of the other
+			// validator in the same transaction, or in its own.
+			name:    "commission through authz",
+			account: commissionAccount,
+			txs: []*sdk.TxResponse{
+				getTx(
+					t, 15, 0,
+					[]abci.Event{
+						getWithdrawalEvent(distributiontypes.EventTypeWithdrawCommission, "", "9000000uatom"),
+						getWithdrawalEvent(distributiontypes.EventTypeWithdrawCommission, "", "2000000uatom"),
+					},
+					&authz.MsgExec{
+						Grantee: "grantee",
+						Msgs: []*codectypes.Any{
+							packMsg(t, &distributiontypes.MsgWithdrawValidatorCommission{ValidatorAddress: "other"}),
+							packMsg(t, &distributiontypes.MsgWithdrawValidatorCommission{ValidatorAddress: "validator"}),
+						},
+					},
+				),
+				getTx(
+					t, 16, 0,
+					[]abci.Event{getWithdrawalEvent(distributiontypes.EventTypeWithdrawCommission, "", "7000000uatom")},
+					&distributiontypes.MsgWithdrawValidatorCommission{ValidatorAddress: "other"},
+				),
+			},
+			expected: []LedgerEntry{
+				{Date: "2024-01-01", Address: "validator", Kind: ledgerKindCommission, Denom: "atom", Earned: 4, Withdrawn: 2},
+				{Date: "2024-01-01", Address: "validator", Kind: ledgerKindCommission, Denom: "ibc/ABC", Earned: 5},
+				{Date: "2024-01-02", Address: "validator", Kind: ledgerKindCommission, Denom: "atom", Earned: -2.5},
+				{Date: "2024-01-02", Address: "validator", Kind: ledgerKindCommission, Denom: "ibc/ABC", Earned: -5},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := &reportConn{outstanding: outstanding, txs: test.txs}
+
+			entries, err := getLedgerEntries(context.Background(), conn, test.account, periods)
+			if err != nil {
+				t.Fatalf("could not get the entries: %s", err)
+			}
+
+			if !reflect.DeepEqual(entries, test.expected) {
+				t.Errorf("got entries %+v, expected %+v", entries, test.expected)
+			}
+		})
+	}
+}
+
+func TestLoadPrices(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string]map[string]float64
+		err      bool
+	}{
+		{
+			name:    "with a header",
+			content: "date,denom,price\n2024-01-01,atom,9.87\n2024-01-01,ibc/ABC,0.5\n2024-01-02,atom,10\n",
+			expected: map[string]map[string]float64{
+				"2024-01-01": {"atom": 9.87, "ibc/ABC": 0.5},
+				"2024-01-02": {"atom": 10},
+			},
+		},
+		{
+			name:    "without a header",
+			content: "2024-01-01,atom,9.87\n",
+			expected: map[string]map[string]float64{
+				"2024-01-01": {"atom": 9.87},
+			},
+		},
+		{
+			name:    "invalid price",
+			content: "2024-01-01,atom,9.87\n2024-01-02,atom,unknown\n",
+			err:     true,
+		},
+		{
+			name:    "missing column",
+			content: "2024-01-01,atom\n",
+			err:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "prices.csv")
+			if err := os.WriteFile(path, []byte(test.content), 0o600); err != nil {
+				t.Fatalf("could not write the prices: %s", err)
+			}
+
+			prices, err := loadPrices(path)
+			if test.err {
+				if err == nil {
+					t.Errorf("expected an error, got prices %v", prices)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("could not load the prices: %s", err)
+			}
+
+			if !reflect.DeepEqual(prices, test.expected) {
+				t.Errorf("got prices %v, expected %v", prices, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	RulesChain             string
+	RulesValidators        []string
+	RulesMissedBlocksRatio float64
+	RulesJailMargin        float64
+	RulesRankMargin        int
+	RulesOutput            string
+)
+
+// RulesOptions parameterise the generated Prometheus rules.
+type RulesOptions struct {
+	// Chain is the chain_id the rules are limited to, all chains if empty.
+	Chain string
+	// Validators are the validator operator addresses the alerts are
+	// limited to, all the exported validators if empty.
+	Validators []string
+	// MissedBlocksRatio is the share of the signing window a validator can
+	// miss before it's alerted about.
+	MissedBlocksRatio float64
+	// JailMargin is how close to the downtime jailing threshold, as a share
+	// of it, a validator is alerted about as about to be jailed.
+	JailMargin float64
+	// RankMargin is how many places above the last active one a validator
+	// is alerted about as about to be dropped from the active set.
+	RankMargin int
+}
+
+type PrometheusRules struct {
+	Groups []PrometheusRuleGroup `yaml:"groups"`
+}
+
+type PrometheusRuleGroup struct {
+	Name  string           `yaml:"name"`
+	Rules []PrometheusRule `yaml:"rules"`
+}
+
+type PrometheusRule struct {
+	Record      string            `yaml:"record,omitempty"`
+	Alert       string            `yaml:"alert,omitempty"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// ruleMetricRegexp matches the exporter metrics used in the rules, but not
+// the recorded ones, which have colons in their names.
+var ruleMetricRegexp = regexp.MustCompile(`\bcosmos_[a-z_]+\b`)
+
+// generatePrometheusRules returns the recording and the alerting rules for
+// the metrics of the /metrics/validator and /metrics/params endpoints. The
+// missed blocks are always compared to the signing window and the jailing
+// threshold of the chain, not to a fixed number of blocks.
+func generatePrometheusRules(options RulesOptions) PrometheusRules {
+	chainSelector := getRulesSelector(options.Chain, nil)
+	validatorSelector := getRulesSelector(options.Chain, options.Validators)
+
+	// Several exporters can export the params of the same chain, so they're
+	// deduplicated before being joined with the validator metrics.
+	param := func(name string) string {
+		return fmt.Sprintf("max by (chain_id) (%s%s)", name, chainSelector)
+	}
+
+	missedBlocksRatio := fmt.Sprintf(
+		"cosmos_validator_missed_blocks%s / on (chain_id) group_left %s",
+		validatorSelector,
+		param("cosmos_params_signed_blocks_window"),
+	)
+
+	return PrometheusRules{
+		Groups: []PrometheusRuleGroup{
+			{
+				Name: "cosmos-exporter.rules",
+				Rules: []PrometheusRule{
+					{
+						Record: "cosmos:validator_missed_blocks:ratio",
+						Expr:   missedBlocksRatio,
+					},
+					{
+						Record: "cosmos:validator_uptime:ratio",
+						Expr:   "1 - cosmos:validator_missed_blocks:ratio",
+					},
+					{
+						Record: "cosmos:validator_delegator_apr:avg1d",
+						Expr:   fmt.Sprintf("avg_over_time(cosmos_validator_delegator_apr%s[1d])", validatorSelector),
+					},
+					{
+						Record: "cosmos:general_nominal_apr:avg1d",
+						Expr:   fmt.Sprintf("avg_over_time(cosmos_general_nominal_apr%s[1d])", chainSelector),
+					},
+				},
+			},
+			{
+				Name: "cosmos-exporter.alerts",
+				Rules: []PrometheusRule{
+					{
+						Alert:  "CosmosValidatorJailed",
+						Expr:   fmt.Sprintf("cosmos_validator_jailed%s == 1", validatorSelector),
+						For:    "1m",
+						Labels: map[string]string{"severity": "critical"},
+						Annotations: map[string]string{
+							"summary": "Validator {{ $labels.moniker }} is jailed on {{ $labels.chain_id }}",
+						},
+					},
+					{
+						Alert: "CosmosValidatorMissingBlocks",
+						Expr: fmt.Sprintf(
+							"cosmos:validator_missed_blocks:ratio > %s",
+							formatRulesFloat(options.MissedBlocksRatio),
+						),
+						For:    "5m",
+						Labels: map[string]string{"severity": "warning"},
+						Annotations: map[string]string{
+							"summary": "Validator {{ $labels.moniker }} missed {{ $value | humanizePercentage }} of the signing window on {{ $labels.chain_id }}",
+						},
+					},
+					{
+						Alert: "CosmosValidatorAboutToBeJailed",
+						Expr: fmt.Sprintf(
+							"cosmos:validator_missed_blocks:ratio > on (chain_id) group_left () ((1 - %s) * %s)",
+							param("cosmos_params_min_signed_per_window"),
+							formatRulesFloat(options.JailMargin),
+						),
+						Labels: map[string]string{"severity": "critical"},
+						Annotations: map[string]string{
+							"summary": "Validator {{ $labels.moniker }} missed {{ $value | humanizePercentage }} of the signing window on {{ $labels.chain_id }} and is about to be jailed for downtime",
+						},
+					},
+					{
+						Alert:  "CosmosValidatorInactive",
+						Expr:   fmt.Sprintf("cosmos_validator_active%s == 0", validatorSelector),
+						For:    "5m",
+						Labels: map[string]string{"severity": "critical"},
+						Annotations: map[string]string{
+							"summary": "Validator {{ $labels.moniker }} is not in the active set on {{ $labels.chain_id }}",
+						},
+					},
+					{
+						Alert: "CosmosValidatorNearActiveSetEdge",
+						Expr: fmt.Sprintf(
+							"cosmos_validator_bonded_rank%s > on (chain_id) group_left %s - %d",
+							validatorSelector,
+							param("cosmos_params_max_validators"),
+							options.RankMargin,
+						),
+						For:    "15m",
+						Labels: map[string]string{"severity": "warning"},
+						Annotations: map[string]string{
+							"summary": "Validator {{ $labels.moniker }} is ranked {{ $value }} on {{ $labels.chain_id }}, close to being dropped from the active set",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func getRulesSelector(chain string, validators []string) string {
+	var matchers []string
+	if chain != "" {
+		matchers = append(matchers, fmt.Sprintf("chain_id=%q", chain))
+	}
+
+	if len(validators) > 0 {
+		addresses := append([]string{}, validators...)
+		sort.Strings(addresses)
+
+		for index, address := range addresses {
+			addresses[index] = regexp.QuoteMeta(address)
+		}
+
+		matchers = append(matchers, fmt.Sprintf("address=~%q", strings.Join(addresses, "|")))
+	}
+
+	if len(matchers) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(matchers, ", ") + "}"
+}
+
+func formatRulesFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// getRulesMetrics returns the exporter metrics the rules use.
+func getRulesMetrics(rules PrometheusRules) []string {
+	seen := map[string]bool{}
+	for _, group := range rules.Groups {
+		for _, rule := range group.Rules {
+			for _, name := range ruleMetricRegexp.FindAllString(rule.Expr, -1) {
+				seen[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func writePrometheusRules(w io.Writer, rules PrometheusRules) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(rules); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Generate the Prometheus recording and alerting rules for the exported metrics",
+	Long: "Write a Prometheus rules file with the recording rules for the validators uptime and APR, " +
+		"and the alerts for jailed, inactive and downtime-jailing-bound validators, " +
+		"for the metrics of the /metrics/validator and /metrics/params endpoints.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		setLogger()
+
+		if RulesMissedBlocksRatio <= 0 || RulesMissedBlocksRatio >= 1 {
+			log.Fatal().Float64("missed-blocks-ratio", RulesMissedBlocksRatio).Msg("Missed blocks ratio must be between 0 and 1")
+		}
+
+		if RulesJailMargin <= 0 || RulesJailMargin > 1 {
+			log.Fatal().Float64("jail-margin", RulesJailMargin).Msg("Jail margin must be between 0 and 1")
+		}
+
+		rules := generatePrometheusRules(RulesOptions{
+			Chain:             RulesChain,
+			Validators:        RulesValidators,
+			MissedBlocksRatio: RulesMissedBlocksRatio,
+			JailMargin:        RulesJailMargin,
+			RankMargin:        RulesRankMargin,
+		})
+
+		output := os.Stdout
+		if RulesOutput != "" {
+			file, err := os.Create(RulesOutput)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Could not create the output file")
+			}
+			defer file.Close()
+
+			output = file
+		}
+
+		if err := writePrometheusRules(output, rules); err != nil {
+			log.Fatal().Err(err).Msg("Could not write rules")
+		}
+	},
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "Update the golden files")
+
+func TestGeneratePrometheusRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		golden  string
+		options RulesOptions
+	}{
+		{
+			name:   "all validators",
+			golden: "rules.golden.yaml",
+			options: RulesOptions{
+				MissedBlocksRatio: 0.05,
+				JailMargin:        0.8,
+				RankMargin:        5,
+			},
+		},
+		{
+			name:   "chain and validators",
+			golden: "rules_validators.golden.yaml",
+			options: RulesOptions{
+				Chain:             "core-1",
+				Validators:        []string{"persistencevaloper1yyy", "persistencevaloper1xxx"},
+				MissedBlocksRatio: 0.1,
+				JailMargin:        0.5,
+				RankMargin:        3,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := writePrometheusRules(&buffer, generatePrometheusRules(test.options)); err != nil {
+				t.Fatalf("could not write rules: %s", err)
+			}
+
+			path := filepath.Join("testdata", test.golden)
+			if *updateGolden {
+				if err := os.WriteFile(path, buffer.Bytes(), 0o644); err != nil {
+					t.Fatalf("could not update %s: %s", path, err)
+				}
+			}
+
+			expected, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("could not read %s: %s", path, err)
+			}
+
+			if !bytes.Equal(buffer.Bytes(), expected) {
+				t.Errorf("rules differ from %s, run go test -run TestGeneratePrometheusRules -update-golden if expected:\n%s", path, buffer.String())
+			}
+		})
+	}
+}
+
+// TestPrometheusRulesExprs checks that the expressions of the rules are
+// valid PromQL, with promtool if it's installed.
+func TestPrometheusRulesExprs(t *testing.T) {
+	for _, options := range []RulesOptions{
+		{MissedBlocksRatio: 0.05, JailMargin: 0.8, RankMargin: 5},
+		{Chain: "core-1", Validators: []string{"persistencevaloper1xxx"}, MissedBlocksRatio: 0.1, JailMargin: 0.5, RankMargin: 3},
+	} {
+		for _, group := range generatePrometheusRules(options).Groups {
+			for _, rule := range group.Rules {
+				if err := checkPromQLExpr(rule.Expr); err != nil {
+					t.Errorf("invalid expression of %s%s: %s", rule.Record, rule.Alert, err)
+				}
+			}
+		}
+	}
+
+	promtool, err := exec.LookPath("promtool")
+	if err != nil {
+		t.Log("promtool not found, only checking the structure of the expressions")
+		return
+	}
+
+	goldens, err := filepath.Glob(filepath.Join("testdata", "rules*.golden.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output, err := exec.Command(promtool, append([]string{"check", "rules"}, goldens...)...).CombinedOutput(); err != nil {
+		t.Errorf("promtool check rules failed: %s\n%s", err, output)
+	}
+}
+
+func TestCheckPromQLExpr(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		valid bool
+	}{
+		{name: "selector", expr: `cosmos_validator_jailed{address=~"a|b"} == 1`, valid: true},
+		{name: "group left without labels", expr: "a > on (chain_id) group_left max by (chain_id) (b) - 5", valid: true},
+		{name: "group left with labels", expr: "a / on (chain_id) group_left (moniker, address) b", valid: true},
+		{name: "empty group left", expr: "a > on (chain_id) group_left () ((1 - b) * 0.8)", valid: true},
+		{name: "expression as group left labels", expr: "a > on (chain_id) group_left (1 - b) * 0.8"},
+		{name: "on without labels", expr: "a > on b"},
+		{name: "unbalanced parentheses", expr: "max by (chain_id) (b"},
+		{name: "unbalanced braces", expr: `b{chain_id="core-1"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkPromQLExpr(test.expr)
+			if test.valid && err != nil {
+				t.Errorf("expected valid, got %s", err)
+			}
+
+			if !test.valid && err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+var (
+	promQLStringRegexp    = regexp.MustCompile(`"(\\.|[^"\\])*"`)
+	promQLModifierRegexp  = regexp.MustCompile(`\b(on|ignoring|by|without|group_left|group_right)\b\s*(\()?`)
+	promQLLabelListRegexp = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*\s*(,\s*[a-zA-Z_][a-zA-Z0-9_]*\s*)*)?$`)
+)
+
+// checkPromQLExpr catches the mistakes a PromQL parser would reject that
+// are easy to make when building the expressions: unbalanced brackets, and
+// modifiers without a label list or with an expression in place of it.
+func checkPromQLExpr(expr string) error {
+	expr = promQLStringRegexp.ReplaceAllString(expr, `""`)
+
+	closing := map[rune]rune{'(': ')', '{': '}', '[': ']'}
+	var stack []rune
+	for _, char := range expr {
+		switch char {
+		case '(', '{', '[':
+			stack = append(stack, closing[char])
+		case ')', '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != char {
+				return fmt.Errorf("unexpected %c", char)
+			}
+
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if len(stack) > 0 {
+		return fmt.Errorf("missing %c", stack[len(stack)-1])
+	}
+
+	for _, match := range promQLModifierRegexp.FindAllStringSubmatchIndex(expr, -1) {
+		modifier := expr[match[2]:match[3]]
+		if match[4] == -1 {
+			if modifier == "group_left" || modifier == "group_right" {
+				continue
+			}
+
+			return fmt.Errorf("%s without a label list", modifier)
+		}
+
+		end := strings.IndexRune(expr[match[5]:], ')')
+		if labels := expr[match[5] : match[5]+end]; !promQLLabelListRegexp.MatchString(labels) {
+			return fmt.Errorf("%s has %q in place of a label list", modifier, labels)
+		}
+	}
+
+	return nil
+}
+
+// TestPrometheusRulesMetrics checks that all the metrics the rules use are
+// still exported under the same names.
+func TestPrometheusRulesMetrics(t *testing.T) {
+	sources := getExporterSources(t, "rules.go")
+
+	rules := generatePrometheusRules(RulesOptions{MissedBlocksRatio: 0.05, JailMargin: 0.8, RankMargin: 5})
+	for _, name := range getRulesMetrics(rules) {
+		if !isMetricExported(sources, name) {
+			t.Errorf("metric %s used in the rules is not exported", name)
+		}
+	}
+}
+
+// getExporterSources returns the non-test sources but the excluded file, to
+// look for the exported metrics in.
+func getExporterSources(t *testing.T, excluded string) string {
+	t.Helper()
+
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sources strings.Builder
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || file == excluded {
+			continue
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sources.Write(content)
+	}
+
+	return sources.String()
+}
+
+func isMetricExported(sources string, name string) bool {
+	return strings.Contains(sources, `Name:        "`+name+`"`)
+}
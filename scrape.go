@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Scrape holds the data shared by all the validators and wallets exported
+// in a single request, so it's queried only once however many of them
+// there are. All of its queries are pinned to the same height, so a single
+// scrape never mixes data from different blocks.
+type Scrape struct {
+	GrpcConn NodeConn
+	// Height is the block height the queries are pinned to, 0 if it could
+	// not be found and the queries go to the latest height instead.
+	Height int64
+	// BlockTime is the time of the block at Height, set when the scrape is
+	// of a past height, such as when backfilling.
+	BlockTime time.Time
+
+	validatorsOnce sync.Once
+	validators     []stakingtypes.Validator
+	validatorsErr  error
+
+	economicsOnce sync.Once
+	economics     *StakingEconomics
+	economicsErr  error
+
+	consensusValidatorsOnce sync.Once
+	consensusValidators     map[string]int64
+	consensusValidatorsErr  error
+}
+
+// latestHeightMaxAge is how long the latest height is reused for, about a
+// block, so the endpoints scraped at the same time and the background
+// refreshes don't each ask the node for it.
+const latestHeightMaxAge = 5 * time.Second
+
+type latestHeightCache struct {
+	mutex     sync.Mutex
+	height    int64
+	fetchedAt time.Time
+}
+
+var latestHeight = &latestHeightCache{}
+
+// Get returns the latest height of the node, asking it only if the last one
+// is older than latestHeightMaxAge. The concurrent callers wait for the
+// same query.
+func (c *latestHeightCache) Get(grpcConn NodeConn) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.height != 0 && time.Since(c.fetchedAt) < latestHeightMaxAge {
+		return c.height, nil
+	}
+
+	height, err := getLatestHeight(context.Background(), grpcConn)
+	if err != nil {
+		return 0, err
+	}
+
+	c.height = height
+	c.fetchedAt = time.Now()
+
+	return height, nil
+}
+
+// NewScrape pins the scrape to the latest height of the node.
+func NewScrape(grpcConn NodeConn) *Scrape {
+	height, err := latestHeight.Get(grpcConn)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not get the latest height, not pinning the queries to it")
+	}
+
+	return &Scrape{GrpcConn: grpcConn, Height: height}
+}
+
+// Context returns the context to send the queries with, asking the node to
+// answer them at the pinned height.
+// Now returns the time the amounts depending on it, such as the maturing
+// or the vesting ones, are computed at: the block time of a past height,
+// or the current time.
+func (s *Scrape) Now() time.Time {
+	if !s.BlockTime.IsZero() {
+		return s.BlockTime
+	}
+
+	return time.Now()
+}
+
+func (s *Scrape) Context() context.Context {
+	if s.Height == 0 {
+		return context.Background()
+	}
+
+	return metadata.AppendToOutgoingContext(
+		context.Background(),
+		grpctypes.GRPCBlockHeightHeader,
+		strconv.FormatInt(s.Height, 10),
+	)
+}
+
+// Validators returns the whole validator set, sorted by tokens. The returned
+// slice is shared, so it must not be modified.
+func (s *Scrape) Validators() ([]stakingtypes.Validator, error) {
+	s.validatorsOnce.Do(func() {
+		s.validators, s.validatorsErr = getAllValidators(s.Context(), s.GrpcConn)
+		if s.validatorsErr == nil {
+			sortValidatorsByTokens(s.validators)
+		}
+	})
+
+	return s.validators, s.validatorsErr
+}
+
+func (s *Scrape) StakingEconomics() (*StakingEconomics, error) {
+	s.economicsOnce.Do(func() {
+		s.economics, s.economicsErr = getStakingEconomics(s.Context(), s.GrpcConn)
+	})
+
+	return s.economics, s.economicsErr
+}
+
+func (s *Scrape) ConsensusValidators() (map[string]int64, error) {
+	s.consensusValidatorsOnce.Do(func() {
+		s.consensusValidators, s.consensusValidatorsErr = getConsensusValidators(s.Height)
+	})
+
+	return s.consensusValidators, s.consensusValidatorsErr
+}
+
+func newSnapshotHeightGauge() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_exporter_snapshot_height",
+			Help:        "Block height the queries of the scrape or of the background refresh were pinned to",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"source"},
+	)
+}
+
+// setSnapshotHeight sets the height of the source, which is either "scrape"
+// or the name of a background refresh. An unpinned height is not exported.
+func setSnapshotHeight(gauge *prometheus.GaugeVec, source string, height int64) {
+	if height == 0 {
+		return
+	}
+
+	gauge.With(prometheus.Labels{"source": source}).Set(float64(height))
+}
+
+// Gatherer returns the registry with the height the scrape was pinned to,
+// for the handlers exporting the targets from several registries.
+func (s *Scrape) Gatherer() prometheus.Gatherer {
+	snapshotHeightGauge := newSnapshotHeightGauge()
+	setSnapshotHeight(snapshotHeightGauge, "scrape", s.Height)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(snapshotHeightGauge)
+
+	return registry
+}
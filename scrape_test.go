@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+)
+
+// heightConn answers every query with the height header only, counting them.
+type heightConn struct {
+	height  string
+	queries int
+}
+
+func (c *heightConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	c.queries++
+
+	for _, opt := range opts {
+		if header, ok := opt.(grpc.HeaderCallOption); ok {
+			*header.HeaderAddr = metadata.Pairs(grpctypes.GRPCBlockHeightHeader, c.height)
+		}
+	}
+
+	return nil
+}
+
+func (c *heightConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, nil
+}
+
+func (c *heightConn) Close() error {
+	return nil
+}
+
+func TestLatestHeightCache(t *testing.T) {
+	conn := &heightConn{height: "100"}
+	cache := &latestHeightCache{}
+
+	for i := 0; i < 3; i++ {
+		height, err := cache.Get(conn)
+		if err != nil {
+			t.Fatalf("could not get the height: %s", err)
+		}
+
+		if height != 100 {
+			t.Errorf("got height %d, expected 100", height)
+		}
+	}
+
+	if conn.queries != 1 {
+		t.Errorf("node queried %d times, expected once", conn.queries)
+	}
+
+	conn.height = "101"
+	cache.fetchedAt = time.Now().Add(-latestHeightMaxAge)
+
+	if height, _ := cache.Get(conn); height != 101 {
+		t.Errorf("got height %d after it expired, expected 101", height)
+	}
+
+	if conn.queries != 2 {
+		t.Errorf("node queried %d times, expected twice", conn.queries)
+	}
+}
+
+func TestScrapeNow(t *testing.T) {
+	blockTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if now := (&Scrape{Height: 100, BlockTime: blockTime}).Now(); !now.Equal(blockTime) {
+		t.Errorf("got %s for a past height, expected the block time %s", now, blockTime)
+	}
+
+	before := time.Now()
+	if now := (&Scrape{Height: 100}).Now(); now.Before(before) {
+		t.Errorf("got %s without a block time, expected the current time", now)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/google/uuid"
+)
+
+// ServiceDiscoveryTarget is a target group in the Prometheus HTTP service
+// discovery format.
+type ServiceDiscoveryTarget struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// ServiceDiscoveryHandler lists the configured validators and wallets in the
+// Prometheus http_sd format. With ?validators=all it also lists the validators
+// from the current validator set, optionally filtered by ?status= and limited
+// to the ?top= biggest ones by tokens.
+func ServiceDiscoveryHandler(w http.ResponseWriter, r *http.Request, grpcConn NodeConn) {
+	requestStart := time.Now()
+
+	sublogger := log.With().
+		Str("request-id", uuid.New().String()).
+		Logger()
+
+	host := ServiceDiscoveryHost
+	if host == "" {
+		host = r.Host
+	}
+
+	query := r.URL.Query()
+	status := strings.ToLower(query.Get("status"))
+
+	var top int
+	if value := query.Get("top"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			sublogger.Error().
+				Str("top", value).
+				Err(err).
+				Msg("Could not parse top")
+			http.Error(w, "Could not parse top", http.StatusBadRequest)
+			return
+		}
+		top = parsed
+	}
+
+	validators, err := getAllValidators(context.Background(), grpcConn)
+	if err != nil {
+		sublogger.Error().Err(err).Msg("Could not get validators")
+		http.Error(w, "Could not get validators", http.StatusInternalServerError)
+		return
+	}
+
+	sortValidatorsByTokens(validators)
+
+	validatorsByAddress := make(map[string]stakingtypes.Validator, len(validators))
+	for _, validator := range validators {
+		validatorsByAddress[validator.OperatorAddress] = validator
+	}
+
+	targets := make([]ServiceDiscoveryTarget, 0, len(ValidatorTargets)+len(WalletTargets))
+	listed := make(map[string]bool, len(ValidatorTargets))
+
+	for _, target := range ValidatorTargets {
+		labels := getServiceDiscoveryLabels(target.Labels, "/metrics/validator", target.Address)
+		if validator, ok := validatorsByAddress[target.Address]; ok {
+			labels["moniker"] = sanitizeUTF8(validator.Description.Moniker)
+			labels["status"] = getValidatorStatus(validator)
+		}
+
+		listed[target.Address] = true
+		targets = append(targets, ServiceDiscoveryTarget{
+			Targets: []string{host},
+			Labels:  labels,
+		})
+	}
+
+	for _, target := range WalletTargets {
+		targets = append(targets, ServiceDiscoveryTarget{
+			Targets: []string{host},
+			Labels:  getServiceDiscoveryLabels(target.Labels, "/metrics/wallet", target.Address),
+		})
+	}
+
+	if query.Get("validators") == "all" {
+		var count int
+		for _, validator := range validators {
+			if status != "" && getValidatorStatus(validator) != status {
+				continue
+			}
+
+			count++
+			if top != 0 && count > top {
+				break
+			}
+
+			if listed[validator.OperatorAddress] {
+				continue
+			}
+
+			labels := getServiceDiscoveryLabels(nil, "/metrics/validator", validator.OperatorAddress)
+			labels["moniker"] = sanitizeUTF8(validator.Description.Moniker)
+			labels["status"] = getValidatorStatus(validator)
+
+			targets = append(targets, ServiceDiscoveryTarget{
+				Targets: []string{host},
+				Labels:  labels,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(targets); err != nil {
+		sublogger.Error().Err(err).Msg("Could not write service discovery response")
+	}
+
+	sublogger.Info().
+		Str("method", "GET").
+		Str("endpoint", "/sd").
+		Int("targets", len(targets)).
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
+
+func getServiceDiscoveryLabels(extraLabels map[string]string, metricsPath string, address string) map[string]string {
+	labels := make(map[string]string, len(extraLabels)+4)
+	for name, value := range extraLabels {
+		labels[name] = value
+	}
+
+	labels["__metrics_path__"] = metricsPath
+	labels["__param_address"] = address
+	labels["instance"] = address
+
+	return labels
+}
+
+// getValidatorStatus returns the validator status in lowercase without the
+// enum prefix, for example "bonded".
+func getValidatorStatus(validator stakingtypes.Validator) string {
+	return strings.ToLower(strings.TrimPrefix(validator.Status.String(), "BOND_STATUS_"))
+}
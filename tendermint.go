@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tendermintValidatorsPerPage = 100
+
+// getConsensusValidators returns the hex consensus addresses of the validators
+// in the CometBFT validator set at the height, or the current one if it's 0,
+// mapped to their voting power.
+func getConsensusValidators(height int64) (map[string]int64, error) {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	validators := make(map[string]int64)
+
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/validators?page=%d&per_page=%d", TendermintRPC, page, tendermintValidatorsPerPage)
+		if height != 0 {
+			url += fmt.Sprintf("&height=%d", height)
+		}
+
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		// The errors, such as for a pruned height, would otherwise decode
+		// into an empty validator set.
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+
+		var result struct {
+			Result struct {
+				Validators []struct {
+					Address     string `json:"address"`
+					VotingPower string `json:"voting_power"`
+				} `json:"validators"`
+				Total string `json:"total"`
+			} `json:"result"`
+		}
+
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, err
+		}
+
+		for _, validator := range result.Result.Validators {
+			power, err := strconv.ParseInt(validator.VotingPower, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			validators[strings.ToUpper(validator.Address)] = power
+		}
+
+		total, err := strconv.Atoi(result.Result.Total)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(result.Result.Validators) == 0 || len(validators) >= total {
+			return validators, nil
+		}
+	}
+}
+
+// getEarliestHeight returns the lowest height the node still has the blocks
+// and the state of, which is above 1 on pruned nodes and on chains that were
+// restarted from a genesis export.
+func getEarliestHeight() (int64, error) {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	resp, err := client.Get(fmt.Sprintf("%s/status", TendermintRPC))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Result struct {
+			SyncInfo struct {
+				EarliestBlockHeight string `json:"earliest_block_height"`
+			} `json:"sync_info"`
+		} `json:"result"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(result.Result.SyncInfo.EarliestBlockHeight, 10, 64)
+}
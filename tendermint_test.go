@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGetConsensusValidators(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected map[string]int64
+		err      bool
+	}{
+		{
+			name:   "validator set",
+			status: http.StatusOK,
+			body: `{"result": {"validators": [
+				{"address": "abcd", "voting_power": "10"},
+				{"address": "EF01", "voting_power": "5"}
+			], "total": "2"}}`,
+			expected: map[string]int64{"ABCD": 10, "EF01": 5},
+		},
+		{
+			name:   "pruned height",
+			status: http.StatusInternalServerError,
+			body:   `{"error": {"code": -32603, "message": "height 1 is not available, lowest height is 100"}}`,
+			err:    true,
+		},
+		{
+			name:   "proxy error page",
+			status: http.StatusBadGateway,
+			body:   `<html><body>Bad Gateway</body></html>`,
+			err:    true,
+		},
+	}
+
+	previousRPC := TendermintRPC
+	t.Cleanup(func() {
+		TendermintRPC = previousRPC
+	})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			TendermintRPC = server.URL
+
+			validators, err := getConsensusValidators(1)
+			if test.err {
+				if err == nil {
+					t.Errorf("expected an error, got validators %v", validators)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("could not get the validators: %s", err)
+			}
+
+			if !reflect.DeepEqual(validators, test.expected) {
+				t.Errorf("got validators %v, expected %v", validators, test.expected)
+			}
+		})
+	}
+}
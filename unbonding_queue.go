@@ -0,0 +1,128 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type validatorStakeFlow struct {
+	Moniker string
+	Flow    float64
+}
+
+// UnbondingQueue keeps the chain-wide unbonding schedule and the stake flow of
+// every validator, both refreshed in the background as they are too expensive
+// to build on every scrape.
+type UnbondingQueue struct {
+	mutex sync.RWMutex
+
+	unbondingByDays map[int]float64
+	stakeFlows      map[string]validatorStakeFlow
+	previousTokens  map[string]float64
+	refreshedAt     time.Time
+	height          int64
+}
+
+var unbondingQueue = &UnbondingQueue{}
+
+func (q *UnbondingQueue) Refresh(grpcConn NodeConn) error {
+	scrape := NewScrape(grpcConn)
+	ctx := scrape.Context()
+
+	validators, err := getAllValidators(ctx, grpcConn)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	unbondingByDays := make(map[int]float64)
+	tokens := make(map[string]float64, len(validators))
+	monikers := make(map[string]string, len(validators))
+
+	for _, validator := range validators {
+		value, _ := new(big.Float).SetInt(validator.Tokens.BigInt()).Float64()
+		tokens[validator.OperatorAddress] = value
+		monikers[validator.OperatorAddress] = sanitizeUTF8(validator.Description.Moniker)
+
+		unbondings, err := getAllValidatorUnbondingDelegations(ctx, grpcConn, validator.OperatorAddress)
+		if err != nil {
+			return err
+		}
+
+		for _, unbonding := range unbondings {
+			for _, entry := range unbonding.Entries {
+				value, _ := new(big.Float).SetInt(entry.Balance.BigInt()).Float64()
+				unbondingByDays[getDaysUntil(entry.CompletionTime, now)] += value
+			}
+		}
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.previousTokens != nil {
+		q.stakeFlows = make(map[string]validatorStakeFlow, len(tokens))
+		for address, value := range tokens {
+			q.stakeFlows[address] = validatorStakeFlow{
+				Moniker: monikers[address],
+				Flow:    value - q.previousTokens[address],
+			}
+		}
+	}
+
+	q.unbondingByDays = unbondingByDays
+	q.previousTokens = tokens
+	q.refreshedAt = now
+	q.height = scrape.Height
+
+	return nil
+}
+
+// Export sets the gauges from the last refresh. It does nothing if the queue
+// has not been refreshed yet.
+func (q *UnbondingQueue) Export(
+	unbondingGauge *prometheus.GaugeVec,
+	stakeFlowGauge *prometheus.GaugeVec,
+	refreshedAtGauge prometheus.Gauge,
+	snapshotHeightGauge *prometheus.GaugeVec,
+) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	if q.refreshedAt.IsZero() {
+		return
+	}
+
+	for days, value := range q.unbondingByDays {
+		unbondingGauge.With(prometheus.Labels{
+			"denom": Denom,
+			"days":  strconv.Itoa(days),
+		}).Set(value / DenomCoefficient)
+	}
+
+	for address, flow := range q.stakeFlows {
+		stakeFlowGauge.With(prometheus.Labels{
+			"address": address,
+			"moniker": flow.Moniker,
+			"denom":   Denom,
+		}).Set(flow.Flow / DenomCoefficient)
+	}
+
+	refreshedAtGauge.Set(float64(q.refreshedAt.Unix()))
+	setSnapshotHeight(snapshotHeightGauge, "unbonding-queue", q.height)
+}
+
+// getDaysUntil returns the number of started days left until the given time,
+// or 0 if it is already in the past.
+func getDaysUntil(completionTime time.Time, now time.Time) int {
+	if !completionTime.After(now) {
+		return 0
+	}
+
+	return int(math.Ceil(completionTime.Sub(now).Hours() / 24))
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetDaysUntil(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		completionTime time.Time
+		days           int
+	}{
+		{name: "in the past", completionTime: now.Add(-time.Hour), days: 0},
+		{name: "now", completionTime: now, days: 0},
+		{name: "within the first day", completionTime: now.Add(time.Minute), days: 1},
+		{name: "exactly one day", completionTime: now.Add(24 * time.Hour), days: 1},
+		{name: "just over one day", completionTime: now.Add(24*time.Hour + time.Second), days: 2},
+		{name: "three weeks", completionTime: now.Add(21 * 24 * time.Hour), days: 21},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if days := getDaysUntil(test.completionTime, now); days != test.days {
+				t.Errorf("got %d days, expected %d", days, test.days)
+			}
+		})
+	}
+}
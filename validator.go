@@ -1,18 +1,14 @@
 package main
 
 import (
-	"context"
-	"crypto/ed25519"
+	"encoding/hex"
 	"fmt"
 	"net/http"
-	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"google.golang.org/grpc"
-
-	cosmosed25519 "github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	querytypes "github.com/cosmos/cosmos-sdk/types/query"
 	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
@@ -21,24 +17,53 @@ import (
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
 )
 
-func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.ClientConn) {
+func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn NodeConn) {
 	requestStart := time.Now()
+
 	sublogger := log.With().
 		Str("request-id", uuid.New().String()).
 		Logger()
 
-	address := r.URL.Query().Get("address")
-	myAddress, err := sdk.ValAddressFromBech32(address)
+	addresses := getAddresses(r)
+	scrape := NewScrape(grpcConn)
+	gatherers := collectTargets(getTargets(addresses), collectValidatorMetrics, scrape, sublogger)
+	gatherers = append(gatherers, scrape.Gatherer())
+
+	h := promhttp.HandlerFor(withValues(gatherers), promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+	sublogger.Info().
+		Str("method", "GET").
+		Str("endpoint", "/metrics/validator?address="+strings.Join(addresses, ",")).
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
+
+// collectValidatorMetrics queries the data about a single validator and
+// registers the metrics for it in the given registry.
+func collectValidatorMetrics(
+	registry prometheus.Registerer,
+	scrape *Scrape,
+	address string,
+	sublogger zerolog.Logger,
+) error {
+	grpcConn := scrape.GrpcConn
+	ctx := scrape.Context()
+
+	resolvedAddress, err := resolveAddress(address, scrape.Validators)
 	if err != nil {
 		sublogger.Error().
 			Str("address", address).
 			Err(err).
 			Msg("Could not parse validator address")
-		return
+		return err
 	}
 
+	myAddress := resolvedAddress.Validator
+	address = myAddress.String()
+
 	validatorDelegationsGauge := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cosmos_validator_delegations",
@@ -75,6 +100,15 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 		[]string{"address", "moniker"},
 	)
 
+	validatorDelegatorAPRGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validator_delegator_apr",
+			Help:        "Staking APR of the Cosmos-based blockchain validator delegators, after commission",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker", "inputs"},
+	)
+
 	validatorCommissionGauge := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cosmos_validator_commission",
@@ -93,6 +127,15 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 		[]string{"address", "moniker", "denom"},
 	)
 
+	validatorDelegatorsRewardsGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validator_delegators_rewards",
+			Help:        "Delegators share of the Cosmos-based blockchain validator outstanding rewards, without the commission",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker", "denom"},
+	)
+
 	validatorUnbondingsGauge := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cosmos_validator_unbondings",
@@ -111,6 +154,42 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 		[]string{"address", "moniker", "denom", "redelegated_by", "redelegated_to"},
 	)
 
+	validatorUnbondingsMaturingGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validator_unbondings_maturing",
+			Help:        "Unbondings of the Cosmos-based blockchain validator completing within the period",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker", "denom", "unbonded_by", "within"},
+	)
+
+	validatorUnbondingsNextCompletionGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validator_unbondings_next_completion_time",
+			Help:        "Unix timestamp of the next unbonding completion of the Cosmos-based blockchain validator",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker", "unbonded_by"},
+	)
+
+	validatorRedelegationsMaturingGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validator_redelegations_maturing",
+			Help:        "Redelegations of the Cosmos-based blockchain validator completing within the period",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker", "denom", "redelegated_by", "redelegated_to", "within"},
+	)
+
+	validatorRedelegationsNextCompletionGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validator_redelegations_next_completion_time",
+			Help:        "Unix timestamp of the next redelegation completion of the Cosmos-based blockchain validator",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker", "redelegated_by", "redelegated_to"},
+	)
+
 	validatorMissedBlocksGauge := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cosmos_validator_missed_blocks",
@@ -123,7 +202,16 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 	validatorRankGauge := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cosmos_validator_rank",
-			Help:        "Rank of the Cosmos-based blockchain validator",
+			Help:        "Rank of the Cosmos-based blockchain validator by tokens among the whole validator set",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker"},
+	)
+
+	validatorBondedRankGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validator_bonded_rank",
+			Help:        "Rank of the Cosmos-based blockchain validator by tokens among bonded validators",
 			ConstLabels: ConstLabels,
 		},
 		[]string{"address", "moniker"},
@@ -132,7 +220,25 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 	validatorIsActiveGauge := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cosmos_validator_active",
-			Help:        "1 if the Cosmos-based blockchain validator is in active set, 0 if not",
+			Help:        "1 if the Cosmos-based blockchain validator is bonded, 0 if not",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker"},
+	)
+
+	validatorInConsensusSetGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validator_in_consensus_set",
+			Help:        "1 if the Cosmos-based blockchain validator is in the CometBFT validator set, 0 if not",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker"},
+	)
+
+	validatorActiveMismatchGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validator_active_mismatch",
+			Help:        "1 if the bonded status of the Cosmos-based blockchain validator disagrees with the CometBFT validator set, 0 if not",
 			ConstLabels: ConstLabels,
 		},
 		[]string{"address", "moniker"},
@@ -156,18 +262,26 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 		[]string{"address", "moniker"},
 	)
 
-	registry := prometheus.NewRegistry()
 	registry.MustRegister(validatorDelegationsGauge)
 	registry.MustRegister(validatorTokensGauge)
 	registry.MustRegister(validatorDelegatorSharesGauge)
 	registry.MustRegister(validatorCommissionRateGauge)
+	registry.MustRegister(validatorDelegatorAPRGauge)
 	registry.MustRegister(validatorCommissionGauge)
 	registry.MustRegister(validatorRewardsGauge)
+	registry.MustRegister(validatorDelegatorsRewardsGauge)
 	registry.MustRegister(validatorUnbondingsGauge)
 	registry.MustRegister(validatorRedelegationsGauge)
+	registry.MustRegister(validatorUnbondingsMaturingGauge)
+	registry.MustRegister(validatorUnbondingsNextCompletionGauge)
+	registry.MustRegister(validatorRedelegationsMaturingGauge)
+	registry.MustRegister(validatorRedelegationsNextCompletionGauge)
 	registry.MustRegister(validatorMissedBlocksGauge)
 	registry.MustRegister(validatorRankGauge)
+	registry.MustRegister(validatorBondedRankGauge)
 	registry.MustRegister(validatorIsActiveGauge)
+	registry.MustRegister(validatorInConsensusSetGauge)
+	registry.MustRegister(validatorActiveMismatchGauge)
 	registry.MustRegister(validatorStatusGauge)
 	registry.MustRegister(validatorJailedGauge)
 
@@ -178,7 +292,7 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 
 	stakingClient := stakingtypes.NewQueryClient(grpcConn)
 	validatorResp, err := stakingClient.Validator(
-		context.Background(),
+		ctx,
 		&stakingtypes.QueryValidatorRequest{ValidatorAddr: myAddress.String()},
 	)
 	if err != nil {
@@ -186,7 +300,7 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 			Str("address", address).
 			Err(err).
 			Msg("Could not get validator")
-		return
+		return err
 	}
 
 	validator := validatorResp.Validator
@@ -250,6 +364,23 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 		"moniker": validator.Description.Moniker,
 	}).Set(jailed)
 
+	var active float64
+	if validator.Status == stakingtypes.Bonded {
+		active = 1
+	} else {
+		active = 0
+	}
+	validatorIsActiveGauge.With(prometheus.Labels{
+		"address": validator.OperatorAddress,
+		"moniker": validator.Description.Moniker,
+	}).Set(active)
+
+	consAddr := getValidatorConsAddr(validator, sublogger)
+
+	// Set once queried, to get the delegators share of the rewards.
+	var commissionCoins, rewardCoins sdk.DecCoins
+	var commissionQueried, rewardsQueried bool
+
 	var wg sync.WaitGroup
 
 	wg.Add(1)
@@ -262,7 +393,7 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 
 		stakingClient := stakingtypes.NewQueryClient(grpcConn)
 		stakingRes, err := stakingClient.ValidatorDelegations(
-			context.Background(),
+			ctx,
 			&stakingtypes.QueryValidatorDelegationsRequest{
 				ValidatorAddr: myAddress.String(),
 				Pagination: &querytypes.PageRequest{
@@ -303,6 +434,53 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying staking economics")
+		queryStart := time.Now()
+
+		economics, err := scrape.StakingEconomics()
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get staking economics")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying staking economics")
+
+		if !economics.Minting {
+			return
+		}
+
+		if rate, err := strconv.ParseFloat(validator.Commission.CommissionRates.Rate.String(), 64); err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not parse commission rate")
+		} else {
+			validatorDelegatorAPRGauge.With(prometheus.Labels{
+				"address": validator.OperatorAddress,
+				"moniker": validator.Description.Moniker,
+				"inputs":  delegatorAPRInputs,
+			}).Set(economics.DelegatorAPR(rate))
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if !capabilities.HasService(distributionQueryService) {
+			sublogger.Trace().
+				Str("address", address).
+				Msg("Distribution module is not available, not querying validator commission")
+			return
+		}
+
 		sublogger.Debug().
 			Str("address", address).
 			Msg("Started querying validator commission")
@@ -310,7 +488,7 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 
 		distributionClient := distributiontypes.NewQueryClient(grpcConn)
 		distributionRes, err := distributionClient.ValidatorCommission(
-			context.Background(),
+			ctx,
 			&distributiontypes.QueryValidatorCommissionRequest{ValidatorAddress: myAddress.String()},
 		)
 		if err != nil {
@@ -326,6 +504,9 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 			Float64("request-time", time.Since(queryStart).Seconds()).
 			Msg("Finished querying validator commission")
 
+		commissionCoins = distributionRes.Commission.Commission
+		commissionQueried = true
+
 		for _, commission := range distributionRes.Commission.Commission {
 			if value, err := strconv.ParseFloat(commission.Amount.String(), 64); err != nil {
 				log.Error().
@@ -336,7 +517,7 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 				validatorCommissionGauge.With(prometheus.Labels{
 					"address": address,
 					"moniker": validator.Description.Moniker,
-					"denom":   Denom,
+					"denom":   commission.Denom,
 				}).Set(value / DenomCoefficient)
 			}
 		}
@@ -345,6 +526,13 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if !capabilities.HasService(distributionQueryService) {
+			sublogger.Trace().
+				Str("address", address).
+				Msg("Distribution module is not available, not querying validator rewards")
+			return
+		}
+
 		sublogger.Debug().
 			Str("address", address).
 			Msg("Started querying validator rewards")
@@ -352,7 +540,7 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 
 		distributionClient := distributiontypes.NewQueryClient(grpcConn)
 		distributionRes, err := distributionClient.ValidatorOutstandingRewards(
-			context.Background(),
+			ctx,
 			&distributiontypes.QueryValidatorOutstandingRewardsRequest{ValidatorAddress: myAddress.String()},
 		)
 		if err != nil {
@@ -368,6 +556,9 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 			Float64("request-time", time.Since(queryStart).Seconds()).
 			Msg("Finished querying validator rewards")
 
+		rewardCoins = distributionRes.Rewards.Rewards
+		rewardsQueried = true
+
 		for _, reward := range distributionRes.Rewards.Rewards {
 			if value, err := strconv.ParseFloat(reward.Amount.String(), 64); err != nil {
 				sublogger.Error().
@@ -378,7 +569,7 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 				validatorRewardsGauge.With(prometheus.Labels{
 					"address": address,
 					"moniker": validator.Description.Moniker,
-					"denom":   Denom,
+					"denom":   reward.Denom,
 				}).Set(value / DenomCoefficient)
 			}
 		}
@@ -394,7 +585,7 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 
 		stakingClient := stakingtypes.NewQueryClient(grpcConn)
 		stakingRes, err := stakingClient.ValidatorUnbondingDelegations(
-			context.Background(),
+			ctx,
 			&stakingtypes.QueryValidatorUnbondingDelegationsRequest{ValidatorAddr: myAddress.String()},
 		)
 		if err != nil {
@@ -410,8 +601,10 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 			Float64("request-time", time.Since(queryStart).Seconds()).
 			Msg("Finished querying validator unbonding delegations")
 
+		now := scrape.Now()
 		for _, unbonding := range stakingRes.UnbondingResponses {
 			var sum float64 = 0
+			entries := make([]maturingEntry, 0, len(unbonding.Entries))
 			for _, entry := range unbonding.Entries {
 				if value, err := strconv.ParseFloat(entry.Balance.String(), 64); err != nil {
 					log.Error().
@@ -420,6 +613,10 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 						Msg("Could not convert unbonding delegation entry")
 				} else {
 					sum += value
+					entries = append(entries, maturingEntry{
+						CompletionTime: entry.CompletionTime,
+						Amount:         value,
+					})
 				}
 			}
 
@@ -429,6 +626,25 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 				"denom":       Denom,
 				"unbonded_by": unbonding.DelegatorAddress,
 			}).Set(sum / DenomCoefficient)
+
+			amounts, nextCompletion := getMaturingAmounts(entries, now)
+			for within, amount := range amounts {
+				validatorUnbondingsMaturingGauge.With(prometheus.Labels{
+					"address":     unbonding.ValidatorAddress,
+					"moniker":     validator.Description.Moniker,
+					"denom":       Denom,
+					"unbonded_by": unbonding.DelegatorAddress,
+					"within":      within,
+				}).Set(amount / DenomCoefficient)
+			}
+
+			if !nextCompletion.IsZero() {
+				validatorUnbondingsNextCompletionGauge.With(prometheus.Labels{
+					"address":     unbonding.ValidatorAddress,
+					"moniker":     validator.Description.Moniker,
+					"unbonded_by": unbonding.DelegatorAddress,
+				}).Set(float64(nextCompletion.Unix()))
+			}
 		}
 	}()
 
@@ -442,7 +658,7 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 
 		stakingClient := stakingtypes.NewQueryClient(grpcConn)
 		stakingRes, err := stakingClient.Redelegations(
-			context.Background(),
+			ctx,
 			&stakingtypes.QueryRedelegationsRequest{SrcValidatorAddr: myAddress.String()},
 		)
 		if err != nil {
@@ -458,8 +674,10 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 			Float64("request-time", time.Since(queryStart).Seconds()).
 			Msg("Finished querying validator redelegations")
 
+		now := scrape.Now()
 		for _, redelegation := range stakingRes.RedelegationResponses {
 			var sum float64 = 0
+			entries := make([]maturingEntry, 0, len(redelegation.Entries))
 			for _, entry := range redelegation.Entries {
 				if value, err := strconv.ParseFloat(entry.Balance.String(), 64); err != nil {
 					log.Error().
@@ -468,6 +686,10 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 						Msg("Could not convert redelegation entry")
 				} else {
 					sum += value
+					entries = append(entries, maturingEntry{
+						CompletionTime: entry.RedelegationEntry.CompletionTime,
+						Amount:         value,
+					})
 				}
 			}
 
@@ -478,64 +700,49 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 				"redelegated_by": redelegation.Redelegation.DelegatorAddress,
 				"redelegated_to": redelegation.Redelegation.ValidatorDstAddress,
 			}).Set(sum / DenomCoefficient)
+
+			amounts, nextCompletion := getMaturingAmounts(entries, now)
+			for within, amount := range amounts {
+				validatorRedelegationsMaturingGauge.With(prometheus.Labels{
+					"address":        redelegation.Redelegation.ValidatorSrcAddress,
+					"moniker":        validator.Description.Moniker,
+					"denom":          Denom,
+					"redelegated_by": redelegation.Redelegation.DelegatorAddress,
+					"redelegated_to": redelegation.Redelegation.ValidatorDstAddress,
+					"within":         within,
+				}).Set(amount / DenomCoefficient)
+			}
+
+			if !nextCompletion.IsZero() {
+				validatorRedelegationsNextCompletionGauge.With(prometheus.Labels{
+					"address":        redelegation.Redelegation.ValidatorSrcAddress,
+					"moniker":        validator.Description.Moniker,
+					"redelegated_by": redelegation.Redelegation.DelegatorAddress,
+					"redelegated_to": redelegation.Redelegation.ValidatorDstAddress,
+				}).Set(float64(nextCompletion.Unix()))
+			}
 		}
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if !capabilities.HasService(slashingQueryService) {
+			sublogger.Trace().
+				Str("address", address).
+				Msg("Slashing module is not available, not querying validator signing info")
+			return
+		}
+
 		sublogger.Debug().
 			Str("address", address).
 			Msg("Started querying validator signing info")
 
-		// Попытка получить consensus address через GetConsAddr()
-		consAddr, err := validator.GetConsAddr()
-		if err != nil {
-			sublogger.Debug().
-				Str("address", validator.OperatorAddress).
-				Err(err).
-				Msg("GetConsAddr() failed (known SDK bug), using custom deserialization")
-			
-			// Попытка ручной десериализации ConsensusPubkey
-			if validator.ConsensusPubkey != nil && validator.ConsensusPubkey.TypeUrl == "/cosmos.crypto.ed25519.PubKey" {
-				// Проверяем, что у нас есть достаточно данных
-				if len(validator.ConsensusPubkey.Value) >= 34 {
-					// Пропускаем первые 2 байта (protobuf заголовок) и берем следующие 32 байта
-					keyData := validator.ConsensusPubkey.Value[2:34]
-					
-					// Создаем ed25519 ключ через стандартный пакет
-					cosmosPubKey := cosmosed25519.PubKey{}
-					// Используем стандартный crypto/ed25519 для создания ключа
-					stdPubKey := ed25519.PublicKey(keyData)
-					cosmosPubKey.Key = stdPubKey
-					
-					// Получаем consensus address из десериализованного ключа
-					consAddr = cosmosPubKey.Address()
-					sublogger.Info().
-						Str("address", validator.OperatorAddress).
-						Str("consensus_addr", fmt.Sprintf("%x", consAddr)).
-						Msg("Successfully extracted consensus address via custom deserialization")
-				} else {
-					sublogger.Error().
-						Str("address", validator.OperatorAddress).
-						Int("length", len(validator.ConsensusPubkey.Value)).
-						Msg("Invalid ed25519 pubkey length")
-				}
-			}
-			
-			// Если ручная десериализация не удалась
-			if consAddr == nil {
-				sublogger.Warn().
-					Str("address", validator.OperatorAddress).
-					Msg("Failed to extract consensus address, skipping missed blocks metrics")
-			}
-		}
-		
 		// Если у нас есть consensus address (полученный любым способом), получаем signing info
 		if consAddr != nil {
 			slashingClient := slashingtypes.NewQueryClient(grpcConn)
 			slashingRes, err := slashingClient.SigningInfo(
-				context.Background(),
+				ctx,
 				&slashingtypes.QuerySigningInfoRequest{ConsAddress: string(consAddr)},
 			)
 			if err != nil {
@@ -561,18 +768,10 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 		defer wg.Done()
 		sublogger.Debug().
 			Str("address", address).
-			Msg("Started querying validator rank and active status")
+			Msg("Started querying validator rank")
 		queryStart := time.Now()
 
-		stakingClient := stakingtypes.NewQueryClient(grpcConn)
-		stakingRes, err := stakingClient.Validators(
-			context.Background(),
-			&stakingtypes.QueryValidatorsRequest{
-				Pagination: &querytypes.PageRequest{
-					Limit: Limit,
-				},
-			},
-		)
+		validators, err := scrape.Validators()
 		if err != nil {
 			sublogger.Error().
 				Str("address", address).
@@ -581,26 +780,17 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 			return
 		}
 
-		validators := stakingRes.Validators
-
-		sort.Slice(validators, func(i, j int) bool {
-			firstShares, firstErr := strconv.ParseFloat(validators[i].DelegatorShares.String(), 64)
-			secondShares, secondErr := strconv.ParseFloat(validators[j].DelegatorShares.String(), 64)
-
-			if firstErr != nil || secondErr != nil {
-				sublogger.Error().
-					Err(err).
-					Msg("Error converting delegator shares for sorting")
-				return true
+		var validatorRank, bondedRank, bondedIndex int
+		for index, validatorIterated := range validators {
+			if validatorIterated.Status == stakingtypes.Bonded {
+				bondedIndex++
 			}
 
-			return firstShares > secondShares
-		})
-
-		var validatorRank int
-		for index, validatorIterated := range validators {
 			if validatorIterated.OperatorAddress == validator.OperatorAddress {
 				validatorRank = index + 1
+				if validatorIterated.Status == stakingtypes.Bonded {
+					bondedRank = bondedIndex
+				}
 				break
 			}
 		}
@@ -617,43 +807,92 @@ func ValidatorHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cli
 			"address": validator.OperatorAddress,
 		}).Set(float64(validatorRank))
 
-		paramsRes, err := stakingClient.Params(
-			context.Background(),
-			&stakingtypes.QueryParamsRequest{},
-		)
-		if err != nil {
-			sublogger.Error().
-				Str("address", address).
-				Err(err).
-				Msg("Could not get staking params")
-			return
-		}
-
-		var active float64
-		if validatorRank <= int(paramsRes.Params.MaxValidators) {
-			active = 1
-		} else {
-			active = 0
+		if bondedRank != 0 {
+			validatorBondedRankGauge.With(prometheus.Labels{
+				"moniker": validator.Description.Moniker,
+				"address": validator.OperatorAddress,
+			}).Set(float64(bondedRank))
 		}
 
-		validatorIsActiveGauge.With(prometheus.Labels{
-			"address": validator.OperatorAddress,
-			"moniker": validator.Description.Moniker,
-		}).Set(active)
-
 		sublogger.Debug().
 			Str("address", address).
 			Float64("request-time", time.Since(queryStart).Seconds()).
-			Msg("Finished querying validator rank and active status")
+			Msg("Finished querying validator rank")
 	}()
 
+	if consAddr != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sublogger.Debug().
+				Str("address", address).
+				Msg("Started querying consensus validator set")
+			queryStart := time.Now()
+
+			consensusValidators, err := scrape.ConsensusValidators()
+			if err != nil {
+				sublogger.Error().
+					Str("address", address).
+					Err(err).
+					Msg("Could not get consensus validator set")
+				return
+			}
+
+			sublogger.Debug().
+				Str("address", address).
+				Float64("request-time", time.Since(queryStart).Seconds()).
+				Msg("Finished querying consensus validator set")
+
+			var inConsensusSet float64
+			if _, ok := consensusValidators[strings.ToUpper(hex.EncodeToString(consAddr))]; ok {
+				inConsensusSet = 1
+			} else {
+				inConsensusSet = 0
+			}
+			validatorInConsensusSetGauge.With(prometheus.Labels{
+				"address": validator.OperatorAddress,
+				"moniker": validator.Description.Moniker,
+			}).Set(inConsensusSet)
+
+			var mismatch float64
+			if inConsensusSet != active {
+				mismatch = 1
+				sublogger.Warn().
+					Str("address", validator.OperatorAddress).
+					Str("status", validator.Status.String()).
+					Bool("in_consensus_set", inConsensusSet == 1).
+					Msg("Validator bonded status does not match the consensus validator set")
+			} else {
+				mismatch = 0
+			}
+			validatorActiveMismatchGauge.With(prometheus.Labels{
+				"address": validator.OperatorAddress,
+				"moniker": validator.Description.Moniker,
+			}).Set(mismatch)
+		}()
+	}
+
 	wg.Wait()
 
-	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
-	h.ServeHTTP(w, r)
-	sublogger.Info().
-		Str("method", "GET").
-		Str("endpoint", "/metrics/validator?address="+address).
-		Float64("request-time", time.Since(requestStart).Seconds()).
-		Msg("Request processed")
+	// The outstanding rewards include the commission, what's left of them
+	// goes to the delegators.
+	if rewardsQueried && commissionQueried {
+		for _, reward := range rewardCoins {
+			share := reward.Amount.Sub(commissionCoins.AmountOf(reward.Denom))
+			if value, err := strconv.ParseFloat(share.String(), 64); err != nil {
+				sublogger.Error().
+					Str("address", address).
+					Err(err).
+					Msg("Could not parse delegators rewards")
+			} else {
+				validatorDelegatorsRewardsGauge.With(prometheus.Labels{
+					"address": address,
+					"moniker": validator.Description.Moniker,
+					"denom":   reward.Denom,
+				}).Set(value / DenomCoefficient)
+			}
+		}
+	}
+
+	return nil
 }
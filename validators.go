@@ -2,28 +2,30 @@ package main
 
 import (
 	"bytes"
-	"context"
 	"crypto/ed25519"
+	"encoding/hex"
 	"fmt"
 	"math/big"
 	"net/http"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 	"unicode/utf8"
 
-	"google.golang.org/grpc"
-
 	cosmosed25519 "github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	querytypes "github.com/cosmos/cosmos-sdk/types/query"
 	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
 	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
 )
 
-func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.ClientConn) {
+func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn NodeConn) {
 	requestStart := time.Now()
 
 	sublogger := log.With().
@@ -39,6 +41,15 @@ func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cl
 		[]string{"address", "moniker"},
 	)
 
+	validatorsDelegatorAPRGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validators_delegator_apr",
+			Help:        "Staking APR of the Cosmos-based blockchain validator delegators, after commission",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker", "inputs"},
+	)
+
 	validatorsStatusGauge := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cosmos_validators_status",
@@ -96,7 +107,16 @@ func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cl
 	validatorsRankGauge := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cosmos_validators_rank",
-			Help:        "Rank of the Cosmos-based blockchain validator",
+			Help:        "Rank of the Cosmos-based blockchain validator by tokens among the whole validator set",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker"},
+	)
+
+	validatorsBondedRankGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validators_bonded_rank",
+			Help:        "Rank of the Cosmos-based blockchain validator by tokens among bonded validators",
 			ConstLabels: ConstLabels,
 		},
 		[]string{"address", "moniker"},
@@ -105,7 +125,25 @@ func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cl
 	validatorsIsActiveGauge := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cosmos_validators_active",
-			Help:        "1 if the Cosmos-based blockchain validator is in active set, 0 if not",
+			Help:        "1 if the Cosmos-based blockchain validator is bonded, 0 if not",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker"},
+	)
+
+	validatorsInConsensusSetGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validators_in_consensus_set",
+			Help:        "1 if the Cosmos-based blockchain validator is in the CometBFT validator set, 0 if not",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "moniker"},
+	)
+
+	validatorsActiveMismatchGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_validators_active_mismatch",
+			Help:        "1 if the bonded status of the Cosmos-based blockchain validator disagrees with the CometBFT validator set, 0 if not",
 			ConstLabels: ConstLabels,
 		},
 		[]string{"address", "moniker"},
@@ -113,6 +151,7 @@ func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cl
 
 	registry := prometheus.NewRegistry()
 	registry.MustRegister(validatorsCommissionGauge)
+	registry.MustRegister(validatorsDelegatorAPRGauge)
 	registry.MustRegister(validatorsStatusGauge)
 	registry.MustRegister(validatorsJailedGauge)
 	registry.MustRegister(validatorsTokensGauge)
@@ -120,11 +159,18 @@ func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cl
 	registry.MustRegister(validatorsMinSelfDelegationGauge)
 	registry.MustRegister(validatorsMissedBlocksGauge)
 	registry.MustRegister(validatorsRankGauge)
+	registry.MustRegister(validatorsBondedRankGauge)
 	registry.MustRegister(validatorsIsActiveGauge)
+	registry.MustRegister(validatorsInConsensusSetGauge)
+	registry.MustRegister(validatorsActiveMismatchGauge)
+
+	scrape := NewScrape(grpcConn)
+	ctx := scrape.Context()
 
 	var validators []stakingtypes.Validator
 	var signingInfos []slashingtypes.ValidatorSigningInfo
-	var validatorSetLength uint32
+	var consensusValidators map[string]int64
+	var economics *StakingEconomics
 
 	var wg sync.WaitGroup
 
@@ -136,7 +182,7 @@ func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cl
 
 		stakingClient := stakingtypes.NewQueryClient(grpcConn)
 		validatorsResponse, err := stakingClient.Validators(
-			context.Background(),
+			ctx,
 			&stakingtypes.QueryValidatorsRequest{
 				Pagination: &querytypes.PageRequest{
 					Limit: Limit,
@@ -153,20 +199,23 @@ func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cl
 			Msg("Finished querying validators")
 		validators = validatorsResponse.Validators
 
-		sort.Slice(validators, func(i, j int) bool {
-			return validators[i].DelegatorShares.GT(validators[j].DelegatorShares)
-		})
+		sortValidatorsByTokens(validators)
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if !capabilities.HasService(slashingQueryService) {
+			sublogger.Trace().Msg("Slashing module is not available, not querying signing infos")
+			return
+		}
+
 		sublogger.Debug().Msg("Started querying validators signing infos")
 		queryStart := time.Now()
 
 		slashingClient := slashingtypes.NewQueryClient(grpcConn)
 		signingInfosResponse, err := slashingClient.SigningInfos(
-			context.Background(),
+			ctx,
 			&slashingtypes.QuerySigningInfosRequest{
 				Pagination: &querytypes.PageRequest{
 					Limit: Limit,
@@ -189,25 +238,41 @@ func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cl
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		sublogger.Debug().Msg("Started querying staking params")
+		sublogger.Debug().Msg("Started querying consensus validator set")
 		queryStart := time.Now()
 
-		stakingClient := stakingtypes.NewQueryClient(grpcConn)
-		paramsResponse, err := stakingClient.Params(
-			context.Background(),
-			&stakingtypes.QueryParamsRequest{},
-		)
+		response, err := getConsensusValidators(scrape.Height)
+		if err != nil {
+			sublogger.Error().
+				Err(err).
+				Msg("Could not get consensus validator set")
+			return
+		}
+
+		sublogger.Debug().
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying consensus validator set")
+		consensusValidators = response
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sublogger.Debug().Msg("Started querying staking economics")
+		queryStart := time.Now()
+
+		response, err := getStakingEconomics(ctx, grpcConn)
 		if err != nil {
 			sublogger.Error().
 				Err(err).
-				Msg("Could not get staking params")
+				Msg("Could not get staking economics")
 			return
 		}
 
 		sublogger.Debug().
 			Float64("request-time", time.Since(queryStart).Seconds()).
-			Msg("Finished querying staking params")
-		validatorSetLength = paramsResponse.Params.MaxValidators
+			Msg("Finished querying staking economics")
+		economics = response
 	}()
 
 	wg.Wait()
@@ -217,6 +282,7 @@ func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cl
 		Int("validatorsLength", len(validators)).
 		Msg("Validators info")
 
+	var bondedRank int
 	for index, validator := range validators {
 		moniker := validator.Description.Moniker
 		moniker = sanitizeUTF8(moniker)
@@ -261,66 +327,23 @@ func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cl
 			"denom":   Denom,
 		}).Set(value / DenomCoefficient)
 
-		// Попытка получить консенсусный адрес для метрик пропущенных блоков
-		// Добавляем детальную диагностику
-		if validator.ConsensusPubkey == nil {
-			sublogger.Error().
-				Str("address", validator.OperatorAddress).
-				Str("moniker", moniker).
-				Str("status", validator.Status.String()).
-				Bool("jailed", validator.Jailed).
-				Msg("Validator consensus pubkey is nil, skipping missed blocks metrics")
-		} else {
-			sublogger.Debug().
-				Str("address", validator.OperatorAddress).
-				Str("moniker", moniker).
-				Str("pubkey_type", validator.ConsensusPubkey.TypeUrl).
-				Str("pubkey_value", fmt.Sprintf("%+v", validator.ConsensusPubkey)).
-				Msg("Attempting to get consensus address")
-		}
-		
-		// Попытка получить consensus address через GetConsAddr()
-		consAddr, err := validator.GetConsAddr()
-		if err != nil {
-			sublogger.Debug().
-				Str("address", validator.OperatorAddress).
-				Str("moniker", moniker).
-				Str("status", validator.Status.String()).
-				Bool("jailed", validator.Jailed).
-				Err(err).
-				Msg("GetConsAddr() failed (known SDK bug), using custom deserialization")
-			
-			// Попытка ручной десериализации ConsensusPubkey
-			if validator.ConsensusPubkey != nil && validator.ConsensusPubkey.TypeUrl == "/cosmos.crypto.ed25519.PubKey" {
-				// Проверяем, что у нас есть достаточно данных
-				if len(validator.ConsensusPubkey.Value) >= 34 {
-					// Пропускаем первые 2 байта (protobuf заголовок) и берем следующие 32 байта
-					keyData := validator.ConsensusPubkey.Value[2:34]
-					
-					// Создаем ed25519 ключ через стандартный пакет
-					cosmosPubKey := cosmosed25519.PubKey{}
-					// Используем стандартный crypto/ed25519 для создания ключа
-					stdPubKey := ed25519.PublicKey(keyData)
-					cosmosPubKey.Key = stdPubKey
-					
-					// Получаем consensus address из десериализованного ключа
-					consAddr = cosmosPubKey.Address()
-					sublogger.Info().
-						Str("address", validator.OperatorAddress).
-						Str("moniker", moniker).
-						Str("consensus_addr", fmt.Sprintf("%x", consAddr)).
-						Msg("Successfully extracted consensus address via custom deserialization")
-				} else {
-					sublogger.Error().
-						Str("address", validator.OperatorAddress).
-						Str("moniker", moniker).
-						Int("length", len(validator.ConsensusPubkey.Value)).
-						Msg("Invalid ed25519 pubkey length")
-				}
+		if economics != nil && economics.Minting {
+			if rate, err := strconv.ParseFloat(validator.Commission.CommissionRates.Rate.String(), 64); err != nil {
+				sublogger.Error().
+					Str("address", validator.OperatorAddress).
+					Err(err).
+					Msg("Could not parse commission rate")
+			} else {
+				validatorsDelegatorAPRGauge.With(prometheus.Labels{
+					"address": validator.OperatorAddress,
+					"moniker": moniker,
+					"inputs":  delegatorAPRInputs,
+				}).Set(economics.DelegatorAPR(rate))
 			}
-			
 		}
-		
+
+		consAddr := getValidatorConsAddr(validator, sublogger)
+
 		// Если у нас есть consensus address (полученный любым способом), ищем signing info
 		if consAddr != nil {
 			var signingInfo slashingtypes.ValidatorSigningInfo
@@ -356,21 +379,54 @@ func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cl
 			"moniker": moniker,
 		}).Set(float64(index + 1))
 
-		if validatorSetLength != 0 {
-			var active float64
-			if index+1 <= int(validatorSetLength) {
-				active = 1
+		var active float64
+		if validator.Status == stakingtypes.Bonded {
+			active = 1
+			bondedRank++
+			validatorsBondedRankGauge.With(prometheus.Labels{
+				"address": validator.OperatorAddress,
+				"moniker": moniker,
+			}).Set(float64(bondedRank))
+		} else {
+			active = 0
+		}
+		validatorsIsActiveGauge.With(prometheus.Labels{
+			"address": validator.OperatorAddress,
+			"moniker": moniker,
+		}).Set(active)
+
+		if consensusValidators != nil && consAddr != nil {
+			var inConsensusSet float64
+			if _, ok := consensusValidators[strings.ToUpper(hex.EncodeToString(consAddr))]; ok {
+				inConsensusSet = 1
+			} else {
+				inConsensusSet = 0
+			}
+			validatorsInConsensusSetGauge.With(prometheus.Labels{
+				"address": validator.OperatorAddress,
+				"moniker": moniker,
+			}).Set(inConsensusSet)
+
+			var mismatch float64
+			if inConsensusSet != active {
+				mismatch = 1
+				sublogger.Warn().
+					Str("address", validator.OperatorAddress).
+					Str("moniker", moniker).
+					Str("status", validator.Status.String()).
+					Bool("in_consensus_set", inConsensusSet == 1).
+					Msg("Validator bonded status does not match the consensus validator set")
 			} else {
-				active = 0
+				mismatch = 0
 			}
-			validatorsIsActiveGauge.With(prometheus.Labels{
+			validatorsActiveMismatchGauge.With(prometheus.Labels{
 				"address": validator.OperatorAddress,
 				"moniker": moniker,
-			}).Set(active)
+			}).Set(mismatch)
 		}
 	}
 
-	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	h := promhttp.HandlerFor(prometheus.Gatherers{registry, scrape.Gatherer()}, promhttp.HandlerOpts{})
 	h.ServeHTTP(w, r)
 	sublogger.Info().
 		Str("method", "GET").
@@ -379,6 +435,85 @@ func ValidatorsHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Cl
 		Msg("Request processed")
 }
 
+// sortValidatorsByTokens sorts validators by their bonded tokens, biggest first.
+func sortValidatorsByTokens(validators []stakingtypes.Validator) {
+	sort.SliceStable(validators, func(i, j int) bool {
+		return validators[i].Tokens.GT(validators[j].Tokens)
+	})
+}
+
+// getValidatorConsAddr returns the consensus address of the validator, or nil if
+// it cannot be derived from its consensus pubkey.
+func getValidatorConsAddr(validator stakingtypes.Validator, sublogger zerolog.Logger) sdk.ConsAddress {
+	moniker := sanitizeUTF8(validator.Description.Moniker)
+
+	// Добавляем детальную диагностику
+	if validator.ConsensusPubkey == nil {
+		sublogger.Error().
+			Str("address", validator.OperatorAddress).
+			Str("moniker", moniker).
+			Str("status", validator.Status.String()).
+			Bool("jailed", validator.Jailed).
+			Msg("Validator consensus pubkey is nil, skipping missed blocks metrics")
+		return nil
+	}
+
+	sublogger.Debug().
+		Str("address", validator.OperatorAddress).
+		Str("moniker", moniker).
+		Str("pubkey_type", validator.ConsensusPubkey.TypeUrl).
+		Str("pubkey_value", fmt.Sprintf("%+v", validator.ConsensusPubkey)).
+		Msg("Attempting to get consensus address")
+
+	// Попытка получить consensus address через GetConsAddr()
+	consAddr, err := validator.GetConsAddr()
+	if err == nil {
+		return consAddr
+	}
+
+	sublogger.Debug().
+		Str("address", validator.OperatorAddress).
+		Str("moniker", moniker).
+		Str("status", validator.Status.String()).
+		Bool("jailed", validator.Jailed).
+		Err(err).
+		Msg("GetConsAddr() failed (known SDK bug), using custom deserialization")
+
+	// Попытка ручной десериализации ConsensusPubkey
+	if validator.ConsensusPubkey.TypeUrl != "/cosmos.crypto.ed25519.PubKey" {
+		sublogger.Warn().
+			Str("address", validator.OperatorAddress).
+			Str("moniker", moniker).
+			Str("pubkey_type", validator.ConsensusPubkey.TypeUrl).
+			Msg("Failed to extract consensus address, unsupported pubkey type")
+		return nil
+	}
+
+	// Проверяем, что у нас есть достаточно данных
+	if len(validator.ConsensusPubkey.Value) < 34 {
+		sublogger.Error().
+			Str("address", validator.OperatorAddress).
+			Str("moniker", moniker).
+			Int("length", len(validator.ConsensusPubkey.Value)).
+			Msg("Invalid ed25519 pubkey length")
+		return nil
+	}
+
+	// Пропускаем первые 2 байта (protobuf заголовок) и берем следующие 32 байта
+	cosmosPubKey := cosmosed25519.PubKey{
+		Key: ed25519.PublicKey(validator.ConsensusPubkey.Value[2:34]),
+	}
+
+	consAddr = sdk.ConsAddress(cosmosPubKey.Address())
+	sublogger.Debug().
+		Str("address", validator.OperatorAddress).
+		Str("moniker", moniker).
+		Str("consensus_addr", fmt.Sprintf("%x", consAddr)).
+		Msg("Successfully extracted consensus address via custom deserialization")
+
+	return consAddr
+}
+
 func sanitizeUTF8(input string) string {
 	buf := &bytes.Buffer{}
 	for _, runeValue := range input {
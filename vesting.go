@@ -0,0 +1,51 @@
+package main
+
+import (
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	vestingexported "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	"github.com/cosmos/gogoproto/proto"
+)
+
+// getAccountType returns the short protobuf name of the account type,
+// for example "BaseAccount" or "PeriodicVestingAccount".
+func getAccountType(account sdk.AccountI) string {
+	name := proto.MessageName(account)
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '.' {
+			return name[i+1:]
+		}
+	}
+
+	return name
+}
+
+// getNextUnlock returns the time and the amount of the next discrete unlock
+// event of the vesting account. Continuous vesting accounts unlock
+// gradually and permanently locked accounts never do, so there is no next
+// unlock event for them.
+func getNextUnlock(account vestingexported.VestingAccount, now time.Time) (time.Time, sdk.Coins, bool) {
+	switch vestingAccount := account.(type) {
+	case *vestingtypes.DelayedVestingAccount:
+		endTime := time.Unix(vestingAccount.GetEndTime(), 0)
+		if !endTime.After(now) {
+			return time.Time{}, nil, false
+		}
+
+		return endTime, vestingAccount.GetOriginalVesting(), true
+	case *vestingtypes.PeriodicVestingAccount:
+		periodEnd := time.Unix(vestingAccount.GetStartTime(), 0)
+		for _, period := range vestingAccount.GetVestingPeriods() {
+			periodEnd = periodEnd.Add(time.Duration(period.Length) * time.Second)
+			if periodEnd.After(now) {
+				return periodEnd, period.Amount, true
+			}
+		}
+
+		return time.Time{}, nil, false
+	default:
+		return time.Time{}, nil, false
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingexported "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+)
+
+func TestGetNextUnlock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	coins := func(amount int64) sdk.Coins {
+		return sdk.NewCoins(sdk.NewCoin("uxprt", sdkmath.NewInt(amount)))
+	}
+	baseVesting := func(original sdk.Coins, end time.Time) *vestingtypes.BaseVestingAccount {
+		return &vestingtypes.BaseVestingAccount{
+			BaseAccount:     &authtypes.BaseAccount{},
+			OriginalVesting: original,
+			EndTime:         end.Unix(),
+		}
+	}
+
+	periodic := &vestingtypes.PeriodicVestingAccount{
+		BaseVestingAccount: baseVesting(coins(600), start.Add(3*time.Hour)),
+		StartTime:          start.Unix(),
+		VestingPeriods: vestingtypes.Periods{
+			{Length: 3600, Amount: coins(100)},
+			{Length: 3600, Amount: coins(200)},
+			{Length: 3600, Amount: coins(300)},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		account vestingexported.VestingAccount
+		now     time.Time
+		time    time.Time
+		amount  sdk.Coins
+		ok      bool
+	}{
+		{
+			name:    "delayed before the end",
+			account: &vestingtypes.DelayedVestingAccount{BaseVestingAccount: baseVesting(coins(500), start.Add(time.Hour))},
+			now:     start,
+			time:    start.Add(time.Hour),
+			amount:  coins(500),
+			ok:      true,
+		},
+		{
+			name:    "delayed after the end",
+			account: &vestingtypes.DelayedVestingAccount{BaseVestingAccount: baseVesting(coins(500), start.Add(time.Hour))},
+			now:     start.Add(time.Hour),
+		},
+		{
+			name:    "periodic before the first period ends",
+			account: periodic,
+			now:     start.Add(time.Minute),
+			time:    start.Add(time.Hour),
+			amount:  coins(100),
+			ok:      true,
+		},
+		{
+			name:    "periodic in the middle",
+			account: periodic,
+			now:     start.Add(90 * time.Minute),
+			time:    start.Add(2 * time.Hour),
+			amount:  coins(200),
+			ok:      true,
+		},
+		{
+			name:    "periodic fully vested",
+			account: periodic,
+			now:     start.Add(3 * time.Hour),
+		},
+		{
+			name: "continuous",
+			account: &vestingtypes.ContinuousVestingAccount{
+				BaseVestingAccount: baseVesting(coins(500), start.Add(time.Hour)),
+				StartTime:          start.Unix(),
+			},
+			now: start,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			unlockTime, amount, ok := getNextUnlock(test.account, test.now)
+			if ok != test.ok {
+				t.Fatalf("got ok %t, expected %t", ok, test.ok)
+			}
+
+			if !unlockTime.Equal(test.time) {
+				t.Errorf("next unlock at %s, expected %s", unlockTime, test.time)
+			}
+
+			if !amount.Equal(test.amount) {
+				t.Errorf("next unlock of %s, expected %s", amount, test.amount)
+			}
+		})
+	}
+}
@@ -1,40 +1,69 @@
 package main
 
 import (
-	"context"
 	"math/big"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"google.golang.org/grpc"
-
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingexported "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
 	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
 )
 
-func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.ClientConn) {
+func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn NodeConn) {
 	requestStart := time.Now()
 
 	sublogger := log.With().
 		Str("request-id", uuid.New().String()).
 		Logger()
 
-	address := r.URL.Query().Get("address")
-	myAddress, err := sdk.AccAddressFromBech32(address)
+	addresses := getAddresses(r)
+	scrape := NewScrape(grpcConn)
+	gatherers := collectTargets(getTargets(addresses), collectWalletMetrics, scrape, sublogger)
+	gatherers = append(gatherers, scrape.Gatherer())
+
+	h := promhttp.HandlerFor(withValues(gatherers), promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+	sublogger.Info().
+		Str("method", "GET").
+		Str("endpoint", "/metrics/wallet?address="+strings.Join(addresses, ",")).
+		Float64("request-time", time.Since(requestStart).Seconds()).
+		Msg("Request processed")
+}
+
+// collectWalletMetrics queries the data about a single wallet and registers
+// the metrics for it in the given registry.
+func collectWalletMetrics(
+	registry prometheus.Registerer,
+	scrape *Scrape,
+	address string,
+	sublogger zerolog.Logger,
+) error {
+	grpcConn := scrape.GrpcConn
+	ctx := scrape.Context()
+
+	resolvedAddress, err := resolveAddress(address, scrape.Validators)
 	if err != nil {
 		sublogger.Error().
 			Str("address", address).
 			Err(err).
 			Msg("Could not parse address")
-		return
+		return err
 	}
 
+	myAddress := resolvedAddress.Account
+	address = myAddress.String()
+
 	walletBalanceGauge := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cosmos_wallet_balance",
@@ -44,6 +73,51 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 		[]string{"address", "denom"},
 	)
 
+	walletSpendableBalanceGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_spendable_balance",
+			Help:        "Spendable balance of the Cosmos-based blockchain wallet",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "denom"},
+	)
+
+	walletAccountNumberGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_account_number",
+			Help:        "Account number of the Cosmos-based blockchain wallet",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address"},
+	)
+
+	walletSequenceGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_sequence",
+			Help:        "Sequence of the Cosmos-based blockchain wallet, increasing with every transaction sent",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address"},
+	)
+
+	walletPubkeySetGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_pubkey_set",
+			Help:        "1 if the Cosmos-based blockchain wallet has its pubkey set, 0 if not",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address"},
+	)
+
+	walletAccountInfoGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_account_info",
+			Help:        "Account info of the Cosmos-based blockchain wallet, always 1",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "type"},
+	)
+
 	walletDelegationGauge := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cosmos_wallet_delegations",
@@ -71,6 +145,42 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 		[]string{"address", "denom", "unbonded_from"},
 	)
 
+	walletUnbondingsMaturingGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_unbondings_maturing",
+			Help:        "Unbondings of the Cosmos-based blockchain wallet completing within the period",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "denom", "unbonded_from", "within"},
+	)
+
+	walletUnbondingsNextCompletionGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_unbondings_next_completion_time",
+			Help:        "Unix timestamp of the next unbonding completion of the Cosmos-based blockchain wallet",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "unbonded_from"},
+	)
+
+	walletRedelegationsMaturingGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_redelegations_maturing",
+			Help:        "Redelegations of the Cosmos-based blockchain wallet completing within the period",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "denom", "redelegated_from", "redelegated_to", "within"},
+	)
+
+	walletRedelegationsNextCompletionGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_redelegations_next_completion_time",
+			Help:        "Unix timestamp of the next redelegation completion of the Cosmos-based blockchain wallet",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "redelegated_from", "redelegated_to"},
+	)
+
 	walletRewardsGauge := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "cosmos_wallet_rewards",
@@ -80,12 +190,120 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 		[]string{"address", "denom", "validator_address"},
 	)
 
-	registry := prometheus.NewRegistry()
+	walletRewardsSumGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_rewards_sum",
+			Help:        "Rewards of the Cosmos-based blockchain wallet from all the validators",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "denom"},
+	)
+
+	walletVestingStartTimeGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_vesting_start_time",
+			Help:        "Unix timestamp of the vesting start of the Cosmos-based blockchain wallet",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "type"},
+	)
+
+	walletVestingEndTimeGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_vesting_end_time",
+			Help:        "Unix timestamp of the vesting end of the Cosmos-based blockchain wallet",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "type"},
+	)
+
+	walletVestingOriginalGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_vesting_original",
+			Help:        "Original vesting amount of the Cosmos-based blockchain wallet",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "denom"},
+	)
+
+	walletVestingLockedGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_vesting_locked",
+			Help:        "Amount of the Cosmos-based blockchain wallet that is still vesting",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "denom"},
+	)
+
+	walletVestingUnlockedGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_vesting_unlocked",
+			Help:        "Amount of the Cosmos-based blockchain wallet that has already vested",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "denom"},
+	)
+
+	walletVestingDelegatedVestingGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_vesting_delegated_vesting",
+			Help:        "Delegated vesting amount of the Cosmos-based blockchain wallet",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "denom"},
+	)
+
+	walletVestingDelegatedFreeGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_vesting_delegated_free",
+			Help:        "Delegated vested amount of the Cosmos-based blockchain wallet",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "denom"},
+	)
+
+	walletVestingNextUnlockTimeGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_vesting_next_unlock_time",
+			Help:        "Unix timestamp of the next unlock of the Cosmos-based blockchain vesting wallet",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address"},
+	)
+
+	walletVestingNextUnlockAmountGauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "cosmos_wallet_vesting_next_unlock_amount",
+			Help:        "Amount unlocked at the next unlock of the Cosmos-based blockchain vesting wallet",
+			ConstLabels: ConstLabels,
+		},
+		[]string{"address", "denom"},
+	)
+
 	registry.MustRegister(walletBalanceGauge)
+	registry.MustRegister(walletSpendableBalanceGauge)
+	registry.MustRegister(walletAccountNumberGauge)
+	registry.MustRegister(walletSequenceGauge)
+	registry.MustRegister(walletPubkeySetGauge)
+	registry.MustRegister(walletAccountInfoGauge)
 	registry.MustRegister(walletDelegationGauge)
 	registry.MustRegister(walletUnbondingsGauge)
 	registry.MustRegister(walletRedelegationGauge)
+	registry.MustRegister(walletUnbondingsMaturingGauge)
+	registry.MustRegister(walletUnbondingsNextCompletionGauge)
+	registry.MustRegister(walletRedelegationsMaturingGauge)
+	registry.MustRegister(walletRedelegationsNextCompletionGauge)
 	registry.MustRegister(walletRewardsGauge)
+	registry.MustRegister(walletRewardsSumGauge)
+	registry.MustRegister(walletVestingStartTimeGauge)
+	registry.MustRegister(walletVestingEndTimeGauge)
+	registry.MustRegister(walletVestingOriginalGauge)
+	registry.MustRegister(walletVestingLockedGauge)
+	registry.MustRegister(walletVestingUnlockedGauge)
+	registry.MustRegister(walletVestingDelegatedVestingGauge)
+	registry.MustRegister(walletVestingDelegatedFreeGauge)
+	registry.MustRegister(walletVestingNextUnlockTimeGauge)
+	registry.MustRegister(walletVestingNextUnlockAmountGauge)
 
 	var wg sync.WaitGroup
 
@@ -99,7 +317,7 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 
 		bankClient := banktypes.NewQueryClient(grpcConn)
 		bankRes, err := bankClient.AllBalances(
-			context.Background(),
+			ctx,
 			&banktypes.QueryAllBalancesRequest{Address: myAddress.String()},
 		)
 		if err != nil {
@@ -124,6 +342,41 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying spendable balance")
+		queryStart := time.Now()
+
+		bankClient := banktypes.NewQueryClient(grpcConn)
+		bankRes, err := bankClient.SpendableBalances(
+			ctx,
+			&banktypes.QuerySpendableBalancesRequest{Address: myAddress.String()},
+		)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get spendable balance")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying spendable balance")
+
+		for _, balance := range bankRes.Balances {
+			value, _ := new(big.Float).SetInt(balance.Amount.BigInt()).Float64()
+			walletSpendableBalanceGauge.With(prometheus.Labels{
+				"address": address,
+				"denom":   balance.Denom,
+			}).Set(value / DenomCoefficient)
+		}
+	}()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -134,7 +387,7 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 
 		stakingClient := stakingtypes.NewQueryClient(grpcConn)
 		stakingRes, err := stakingClient.DelegatorDelegations(
-			context.Background(),
+			ctx,
 			&stakingtypes.QueryDelegatorDelegationsRequest{DelegatorAddr: myAddress.String()},
 		)
 		if err != nil {
@@ -170,7 +423,7 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 
 		stakingClient := stakingtypes.NewQueryClient(grpcConn)
 		stakingRes, err := stakingClient.DelegatorUnbondingDelegations(
-			context.Background(),
+			ctx,
 			&stakingtypes.QueryDelegatorUnbondingDelegationsRequest{DelegatorAddr: myAddress.String()},
 		)
 		if err != nil {
@@ -186,11 +439,17 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 			Float64("request-time", time.Since(queryStart).Seconds()).
 			Msg("Finished querying unbonding delegations")
 
+		now := scrape.Now()
 		for _, unbonding := range stakingRes.UnbondingResponses {
 			var sum float64 = 0
+			entries := make([]maturingEntry, 0, len(unbonding.Entries))
 			for _, entry := range unbonding.Entries {
 				value, _ := new(big.Float).SetInt(entry.Balance.BigInt()).Float64()
 				sum += value
+				entries = append(entries, maturingEntry{
+					CompletionTime: entry.CompletionTime,
+					Amount:         value,
+				})
 			}
 
 			walletUnbondingsGauge.With(prometheus.Labels{
@@ -198,6 +457,23 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 				"denom":         Denom, // Нет denoma в ответе, используем глобальный
 				"unbonded_from": unbonding.ValidatorAddress,
 			}).Set(sum / DenomCoefficient)
+
+			amounts, nextCompletion := getMaturingAmounts(entries, now)
+			for within, amount := range amounts {
+				walletUnbondingsMaturingGauge.With(prometheus.Labels{
+					"address":       unbonding.DelegatorAddress,
+					"denom":         Denom,
+					"unbonded_from": unbonding.ValidatorAddress,
+					"within":        within,
+				}).Set(amount / DenomCoefficient)
+			}
+
+			if !nextCompletion.IsZero() {
+				walletUnbondingsNextCompletionGauge.With(prometheus.Labels{
+					"address":       unbonding.DelegatorAddress,
+					"unbonded_from": unbonding.ValidatorAddress,
+				}).Set(float64(nextCompletion.Unix()))
+			}
 		}
 	}()
 
@@ -211,7 +487,7 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 
 		stakingClient := stakingtypes.NewQueryClient(grpcConn)
 		stakingRes, err := stakingClient.Redelegations(
-			context.Background(),
+			ctx,
 			&stakingtypes.QueryRedelegationsRequest{DelegatorAddr: myAddress.String()},
 		)
 		if err != nil {
@@ -227,11 +503,17 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 			Float64("request-time", time.Since(queryStart).Seconds()).
 			Msg("Finished querying redelegations")
 
+		now := scrape.Now()
 		for _, redelegation := range stakingRes.RedelegationResponses {
 			var sum float64 = 0
+			entries := make([]maturingEntry, 0, len(redelegation.Entries))
 			for _, entry := range redelegation.Entries {
 				value, _ := new(big.Float).SetInt(entry.Balance.BigInt()).Float64()
 				sum += value
+				entries = append(entries, maturingEntry{
+					CompletionTime: entry.RedelegationEntry.CompletionTime,
+					Amount:         value,
+				})
 			}
 
 			walletRedelegationGauge.With(prometheus.Labels{
@@ -240,12 +522,38 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 				"redelegated_from": redelegation.Redelegation.ValidatorSrcAddress,
 				"redelegated_to":   redelegation.Redelegation.ValidatorDstAddress,
 			}).Set(sum / DenomCoefficient)
+
+			amounts, nextCompletion := getMaturingAmounts(entries, now)
+			for within, amount := range amounts {
+				walletRedelegationsMaturingGauge.With(prometheus.Labels{
+					"address":          redelegation.Redelegation.DelegatorAddress,
+					"denom":            Denom,
+					"redelegated_from": redelegation.Redelegation.ValidatorSrcAddress,
+					"redelegated_to":   redelegation.Redelegation.ValidatorDstAddress,
+					"within":           within,
+				}).Set(amount / DenomCoefficient)
+			}
+
+			if !nextCompletion.IsZero() {
+				walletRedelegationsNextCompletionGauge.With(prometheus.Labels{
+					"address":          redelegation.Redelegation.DelegatorAddress,
+					"redelegated_from": redelegation.Redelegation.ValidatorSrcAddress,
+					"redelegated_to":   redelegation.Redelegation.ValidatorDstAddress,
+				}).Set(float64(nextCompletion.Unix()))
+			}
 		}
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if !capabilities.HasService(distributionQueryService) {
+			sublogger.Trace().
+				Str("address", address).
+				Msg("Distribution module is not available, not querying rewards")
+			return
+		}
+
 		sublogger.Debug().
 			Str("address", address).
 			Msg("Started querying rewards")
@@ -253,7 +561,7 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 
 		distributionClient := distributiontypes.NewQueryClient(grpcConn)
 		distributionRes, err := distributionClient.DelegationTotalRewards(
-			context.Background(),
+			ctx,
 			&distributiontypes.QueryDelegationTotalRewardsRequest{DelegatorAddress: myAddress.String()},
 		)
 		if err != nil {
@@ -271,23 +579,142 @@ func WalletHandler(w http.ResponseWriter, r *http.Request, grpcConn *grpc.Client
 
 		for _, reward := range distributionRes.Rewards {
 			for _, entry := range reward.Reward {
-				value, _ := new(big.Float).SetInt(entry.Amount.BigInt()).Float64()
-				walletRewardsGauge.With(prometheus.Labels{
-					"address":           address,
-					"denom":             entry.Denom, // Используем реальный denom
-					"validator_address": reward.ValidatorAddress,
+				if value, err := strconv.ParseFloat(entry.Amount.String(), 64); err != nil {
+					sublogger.Error().
+						Str("address", address).
+						Err(err).
+						Msg("Could not parse reward")
+				} else {
+					walletRewardsGauge.With(prometheus.Labels{
+						"address":           address,
+						"denom":             entry.Denom, // Используем реальный denom
+						"validator_address": reward.ValidatorAddress,
+					}).Set(value / DenomCoefficient)
+				}
+			}
+		}
+
+		for _, entry := range distributionRes.Total {
+			if value, err := strconv.ParseFloat(entry.Amount.String(), 64); err != nil {
+				sublogger.Error().
+					Str("address", address).
+					Err(err).
+					Msg("Could not parse total reward")
+			} else {
+				walletRewardsSumGauge.With(prometheus.Labels{
+					"address": address,
+					"denom":   entry.Denom,
 				}).Set(value / DenomCoefficient)
 			}
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sublogger.Debug().
+			Str("address", address).
+			Msg("Started querying account")
+		queryStart := time.Now()
+
+		authClient := authtypes.NewQueryClient(grpcConn)
+		authRes, err := authClient.Account(
+			ctx,
+			&authtypes.QueryAccountRequest{Address: myAddress.String()},
+		)
+		if err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not get account")
+			return
+		}
+
+		var account sdk.AccountI
+		if err := interfaceRegistry.UnpackAny(authRes.Account, &account); err != nil {
+			sublogger.Error().
+				Str("address", address).
+				Err(err).
+				Msg("Could not decode account")
+			return
+		}
+
+		sublogger.Debug().
+			Str("address", address).
+			Float64("request-time", time.Since(queryStart).Seconds()).
+			Msg("Finished querying account")
+
+		accountType := getAccountType(account)
+
+		walletAccountInfoGauge.With(prometheus.Labels{
+			"address": address,
+			"type":    accountType,
+		}).Set(1)
+
+		walletAccountNumberGauge.With(prometheus.Labels{
+			"address": address,
+		}).Set(float64(account.GetAccountNumber()))
+
+		walletSequenceGauge.With(prometheus.Labels{
+			"address": address,
+		}).Set(float64(account.GetSequence()))
+
+		var pubkeySet float64
+		if account.GetPubKey() != nil {
+			pubkeySet = 1
+		} else {
+			pubkeySet = 0
+		}
+		walletPubkeySetGauge.With(prometheus.Labels{
+			"address": address,
+		}).Set(pubkeySet)
+
+		vestingAccount, ok := account.(vestingexported.VestingAccount)
+		if !ok {
+			sublogger.Trace().
+				Str("address", address).
+				Str("type", accountType).
+				Msg("Account is not a vesting account, not returning vesting metrics.")
+			return
+		}
+
+		now := scrape.Now()
+
+		walletVestingStartTimeGauge.With(prometheus.Labels{
+			"address": address,
+			"type":    accountType,
+		}).Set(float64(vestingAccount.GetStartTime()))
+
+		walletVestingEndTimeGauge.With(prometheus.Labels{
+			"address": address,
+			"type":    accountType,
+		}).Set(float64(vestingAccount.GetEndTime()))
+
+		setCoins := func(gauge *prometheus.GaugeVec, coins sdk.Coins) {
+			for _, coin := range coins {
+				value, _ := new(big.Float).SetInt(coin.Amount.BigInt()).Float64()
+				gauge.With(prometheus.Labels{
+					"address": address,
+					"denom":   coin.Denom,
+				}).Set(value / DenomCoefficient)
+			}
+		}
+
+		setCoins(walletVestingOriginalGauge, vestingAccount.GetOriginalVesting())
+		setCoins(walletVestingLockedGauge, vestingAccount.GetVestingCoins(now))
+		setCoins(walletVestingUnlockedGauge, vestingAccount.GetVestedCoins(now))
+		setCoins(walletVestingDelegatedVestingGauge, vestingAccount.GetDelegatedVesting())
+		setCoins(walletVestingDelegatedFreeGauge, vestingAccount.GetDelegatedFree())
+
+		if unlockTime, unlockAmount, ok := getNextUnlock(vestingAccount, now); ok {
+			walletVestingNextUnlockTimeGauge.With(prometheus.Labels{
+				"address": address,
+			}).Set(float64(unlockTime.Unix()))
+			setCoins(walletVestingNextUnlockAmountGauge, unlockAmount)
+		}
+	}()
+
 	wg.Wait()
 
-	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
-	h.ServeHTTP(w, r)
-	sublogger.Info().
-		Str("method", "GET").
-		Str("endpoint", "/metrics/wallet?address="+address).
-		Float64("request-time", time.Since(requestStart).Seconds()).
-		Msg("Request processed")
+	return nil
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// accountConn answers the account query only, failing all the others.
+type accountConn struct {
+	account *codectypes.Any
+}
+
+func (c *accountConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	response, ok := reply.(*authtypes.QueryAccountResponse)
+	if !ok {
+		return fmt.Errorf("unexpected query %s", method)
+	}
+
+	response.Account = c.account
+	return nil
+}
+
+func (c *accountConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, fmt.Errorf("unexpected stream %s", method)
+}
+
+func (c *accountConn) Close() error {
+	return nil
+}
+
+// TestCollectWalletMetricsVesting checks that the vesting amounts of a past
+// height are the ones at its block time, as when backfilling.
+func TestCollectWalletMetricsVesting(t *testing.T) {
+	previousPrefix, previousCoefficient := AccountPrefix, DenomCoefficient
+	AccountPrefix, DenomCoefficient = sdk.GetConfig().GetBech32AccountAddrPrefix(), 1
+	t.Cleanup(func() {
+		AccountPrefix, DenomCoefficient = previousPrefix, previousCoefficient
+	})
+
+	address := sdk.AccAddress([]byte("vesting_____________"))
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	account, err := vestingtypes.NewContinuousVestingAccount(
+		authtypes.NewBaseAccountWithAddress(address),
+		sdk.NewCoins(sdk.NewInt64Coin("uatom", 100)),
+		startTime.Unix(),
+		startTime.Add(100*time.Second).Unix(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accountAny, err := codectypes.NewAnyWithValue(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		blockTime time.Time
+		locked    float64
+		unlocked  float64
+	}{
+		{name: "before the start", blockTime: startTime.Add(-time.Hour), locked: 100, unlocked: 0},
+		{name: "a quarter in", blockTime: startTime.Add(25 * time.Second), locked: 75, unlocked: 25},
+		{name: "after the end", blockTime: startTime.Add(time.Hour), locked: 0, unlocked: 100},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scrape := &Scrape{GrpcConn: &accountConn{account: accountAny}, Height: 100, BlockTime: test.blockTime}
+			registry := prometheus.NewRegistry()
+
+			if err := collectWalletMetrics(registry, scrape, address.String(), zerolog.Nop()); err != nil {
+				t.Fatalf("could not collect: %s", err)
+			}
+
+			families, err := registry.Gather()
+			if err != nil {
+				t.Fatalf("could not gather: %s", err)
+			}
+
+			values := map[string]float64{}
+			for _, family := range families {
+				for _, metric := range family.Metric {
+					values[family.GetName()] += metric.GetGauge().GetValue()
+				}
+			}
+
+			if values["cosmos_wallet_vesting_locked"] != test.locked {
+				t.Errorf("got %f locked, expected %f", values["cosmos_wallet_vesting_locked"], test.locked)
+			}
+
+			if values["cosmos_wallet_vesting_unlocked"] != test.unlocked {
+				t.Errorf("got %f unlocked, expected %f", values["cosmos_wallet_vesting_unlocked"], test.unlocked)
+			}
+		})
+	}
+}